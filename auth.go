@@ -0,0 +1,121 @@
+package cadet
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type Principal any
+
+type principalKey struct{}
+
+var ErrInvalidKey = errors.New("invalid API key")
+
+func BearerAuth(verify func(token string) (Principal, error)) Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "Bearer", "missing bearer token")
+				return
+			}
+
+			principal, err := verify(token)
+			if err != nil {
+				unauthorized(w, "Bearer", "invalid bearer token")
+				return
+			}
+
+			h(w, withPrincipal(r, principal))
+		}
+	}
+}
+
+func APIKey(header string, lookup func(key string) (Principal, error)) Middleware {
+	return apiKey(func(r *http.Request) string {
+		return r.Header.Get(header)
+	}, lookup)
+}
+
+func APIKeyQuery(param string, lookup func(key string) (Principal, error)) Middleware {
+	return apiKey(func(r *http.Request) string {
+		return r.URL.Query().Get(param)
+	}, lookup)
+}
+
+func StaticKeys(keys map[string]Principal) func(key string) (Principal, error) {
+	hashes := make([][sha256.Size]byte, 0, len(keys))
+	principals := make([]Principal, 0, len(keys))
+
+	for key, principal := range keys {
+		hashes = append(hashes, sha256.Sum256([]byte(key)))
+		principals = append(principals, principal)
+	}
+
+	return func(key string) (Principal, error) {
+		hash := sha256.Sum256([]byte(key))
+		match := -1
+
+		for i := range hashes {
+			if subtle.ConstantTimeCompare(hash[:], hashes[i][:]) == 1 {
+				match = i
+			}
+		}
+
+		if match < 0 {
+			return nil, ErrInvalidKey
+		}
+
+		return principals[match], nil
+	}
+}
+
+func apiKey(extract func(r *http.Request) string, lookup func(key string) (Principal, error)) Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := extract(r)
+			if key == "" {
+				unauthorized(w, "", "missing API key")
+				return
+			}
+
+			principal, err := lookup(key)
+			if err != nil {
+				unauthorized(w, "", "invalid API key")
+				return
+			}
+
+			h(w, withPrincipal(r, principal))
+		}
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, challenge string, message string) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+
+	Error(http.StatusUnauthorized, message)(w)
+}
+
+func withPrincipal(r *http.Request, principal Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+}
+
+func principalFrom(ctx context.Context) Principal {
+	return ctx.Value(principalKey{})
+}
@@ -0,0 +1,138 @@
+package cadet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+type CommandInfo struct {
+	Name        string
+	Permissions []string
+	Scopes      []string
+	Meta        Meta
+}
+
+type ScopeError struct {
+	Scope string
+}
+
+func (e *ScopeError) Error() string {
+	return "missing scope " + e.Scope
+}
+
+func (e *ScopeError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+func RequirePermission(permissions ...string) CommandOption {
+	return func(o *commandOptions) {
+		o.permissions = append(o.permissions, permissions...)
+	}
+}
+
+func RequireScope(scopes ...string) CommandOption {
+	return func(o *commandOptions) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+func principalScopes(principal Principal) []string {
+	if p, ok := principal.(interface{ Scopes() []string }); ok {
+		return p.Scopes()
+	}
+
+	claims, ok := principal.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+
+	for _, name := range []string{"scope", "scp"} {
+		switch value := claims[name].(type) {
+		case string:
+			return strings.Fields(value)
+		case []any:
+			scopes := make([]string, 0, len(value))
+			for _, scope := range value {
+				if s, ok := scope.(string); ok {
+					scopes = append(scopes, s)
+				}
+			}
+
+			return scopes
+		}
+	}
+
+	return nil
+}
+
+func checkScopes(principal Principal, required []string) *ScopeError {
+	if len(required) == 0 {
+		return nil
+	}
+
+	granted := principalScopes(principal)
+
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return &ScopeError{scope}
+		}
+	}
+
+	return nil
+}
+
+func scopeDenied(err *ScopeError) Response {
+	return func(w http.ResponseWriter) {
+		type response struct {
+			Error string `json:"error"`
+			Scope string `json:"scope"`
+		}
+
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, err.Scope))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		JSON(&response{"insufficient scope", err.Scope})(w)
+	}
+}
+
+func (s *Server[T]) authorize(w http.ResponseWriter, r *http.Request, handler *command[T], command *Command) bool {
+	info := handler.options.info(command.Name)
+	principal := principalFrom(r.Context())
+
+	request := &Request{command: *command, RawResponse: w, RawRequest: r}
+
+	if err := checkScopes(principal, info.Scopes); err != nil {
+		s.respondError(w, r, request, err, scopeDenied(err))
+		return false
+	}
+
+	var err error
+
+	switch {
+	case s.authorizer != nil:
+		err = s.authorizer(principal, info)
+	case len(info.Permissions) > 0:
+		s.logger.Error("command requires permissions but no Authorize hook is configured", "command", command.Name)
+		err = ErrForbidden
+	}
+
+	if err == nil {
+		return true
+	}
+
+	fallback := Error(http.StatusForbidden, err.Error())
+	if !errors.Is(err, ErrForbidden) {
+		err = fmt.Errorf("%w: %w", ErrForbidden, err)
+	}
+
+	s.respondError(w, r, request, err, fallback)
+
+	return false
+}
@@ -0,0 +1,112 @@
+package cadet
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+type BreakerOptions struct {
+	Threshold     int
+	Cooldown      time.Duration
+	OnStateChange func(command string, from BreakerState, to BreakerState)
+}
+
+type breaker struct {
+	options  BreakerOptions
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	opened   time.Time
+	trial    bool
+}
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+func WithCircuitBreaker(options BreakerOptions) CommandOption {
+	if options.Threshold <= 0 {
+		options.Threshold = 5
+	}
+
+	if options.Cooldown <= 0 {
+		options.Cooldown = 30 * time.Second
+	}
+
+	return func(o *commandOptions) {
+		o.breaker = &breaker{options: options}
+	}
+}
+
+func (b *breaker) allow(command string) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		remaining := b.options.Cooldown - time.Since(b.opened)
+		if remaining > 0 {
+			return false, remaining
+		}
+
+		b.transition(command, BreakerHalfOpen)
+		b.trial = true
+		return true, 0
+	case BreakerHalfOpen:
+		if b.trial {
+			return false, b.options.Cooldown
+		}
+
+		b.trial = true
+	}
+
+	return true, 0
+}
+
+func (b *breaker) record(command string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if success {
+		b.failures = 0
+
+		if b.state != BreakerClosed {
+			b.transition(command, BreakerClosed)
+		}
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == BreakerHalfOpen || b.failures >= b.options.Threshold {
+		b.opened = time.Now()
+		b.transition(command, BreakerOpen)
+	}
+}
+
+func (b *breaker) transition(command string, state BreakerState) {
+	from := b.state
+	b.state = state
+
+	if from != state && b.options.OnStateChange != nil {
+		b.options.OnStateChange(command, from, state)
+	}
+}
@@ -0,0 +1,112 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return &bytes.Buffer{}
+	},
+}
+
+var decoderPools = [2]sync.Pool{
+	{New: func() any { return newJSONDecoder(false) }},
+	{New: func() any { return newJSONDecoder(true) }},
+}
+
+type jsonDecoder struct {
+	source  decoderSource
+	decoder *json.Decoder
+	strict  bool
+}
+
+type decoderSource struct {
+	r    io.Reader
+	read int
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBuffer {
+		return
+	}
+
+	buffer.Reset()
+	bufferPool.Put(buffer)
+}
+
+func newJSONDecoder(strict bool) *jsonDecoder {
+	d := &jsonDecoder{strict: strict}
+	d.decoder = json.NewDecoder(&d.source)
+
+	if strict {
+		d.decoder.DisallowUnknownFields()
+	}
+
+	return d
+}
+
+func getDecoder(r io.Reader, strict bool) *jsonDecoder {
+	index := 0
+	if strict {
+		index = 1
+	}
+
+	d := decoderPools[index].Get().(*jsonDecoder)
+	d.source = decoderSource{r: r}
+
+	return d
+}
+
+func putDecoder(d *jsonDecoder) {
+	read := d.source.read
+	d.source = decoderSource{}
+
+	if read > maxPooledBuffer {
+		return
+	}
+
+	index := 0
+	if d.strict {
+		index = 1
+	}
+
+	decoderPools[index].Put(d)
+}
+
+func (s *decoderSource) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.read += n
+
+	return n, err
+}
+
+func (d *jsonDecoder) decode(v any) error {
+	if err := d.decoder.Decode(v); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		return err
+	}
+
+	if _, err := d.decoder.Token(); err != io.EOF {
+		if errors.Is(err, errBodyTooLarge) {
+			return err
+		}
+
+		return errors.New("unexpected data after command")
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package cadet
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type CacheStore interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool)
+	Set(ctx context.Context, key string, response *CachedResponse, ttl time.Duration)
+}
+
+type memoryCacheEntry struct {
+	response *CachedResponse
+	expires  time.Time
+}
+
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	swept   time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]memoryCacheEntry),
+		swept:   time.Now(),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, response *CachedResponse, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(c.swept) > time.Minute {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		c.swept = now
+	}
+
+	c.entries[key] = memoryCacheEntry{response, now.Add(ttl)}
+}
+
+type cachingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *cachingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cachingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (c *CachedResponse) write(w http.ResponseWriter) {
+	for name, values := range c.Header {
+		w.Header()[name] = values
+	}
+
+	w.WriteHeader(c.Status)
+	w.Write(c.Body)
+}
+
+func WithCache(ttl time.Duration) CommandOption {
+	return func(o *commandOptions) {
+		o.cache = ttl
+	}
+}
+
+func WithCacheKey(key func(r *Request) string) CommandOption {
+	return func(o *commandOptions) {
+		o.cacheKey = key
+	}
+}
+
+func (s *Server[T]) withCache(ttl time.Duration, request *Request, key func(r *Request) string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := ""
+		if s.tenantResolver != nil {
+			var err error
+			if tenant, _, err = s.resolveTenant(r); err != nil {
+				h(w, r)
+				return
+			}
+		}
+
+		request.RawRequest = r
+
+		variant := principalVariant(request.Principal())
+		if key != nil {
+			variant = key(request)
+		}
+
+		withCache(s.cacheStore, ttl, cacheKey(&request.command, tenant, variant), h)(w, r)
+	}
+}
+
+func principalVariant(principal Principal) string {
+	if principal == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%T %+v", principal, principal)
+}
+
+func cacheKey(command *Command, parts ...string) string {
+	data := &bytes.Buffer{}
+	for _, part := range parts {
+		data.WriteString(part)
+		data.WriteByte(0)
+	}
+
+	if json.Compact(data, command.Data) != nil {
+		data.Write(command.Data)
+	}
+
+	hash := sha256.Sum256(data.Bytes())
+	return command.Name + ":" + hex.EncodeToString(hash[:])
+}
+
+func withCache(store CacheStore, ttl time.Duration, key string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cached, ok := store.Get(r.Context(), key); ok {
+			cached.write(w)
+			return
+		}
+
+		before := w.Header().Clone()
+		writer := &cachingWriter{ResponseWriter: w}
+		h(writer, r)
+
+		status := writer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
+		header := headerChanges(before, w.Header())
+		if _, ok := header["Set-Cookie"]; ok {
+			return
+		}
+
+		store.Set(r.Context(), key, &CachedResponse{status, header, writer.body.Bytes()}, ttl)
+	}
+}
+
+func headerChanges(before http.Header, after http.Header) http.Header {
+	changes := make(http.Header)
+
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			changes[name] = slices.Clone(values)
+		}
+	}
+
+	return changes
+}
@@ -2,113 +2,579 @@ package cadet
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
 	"reflect"
 	"regexp"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
 
-type ContentType int
-
-const (
-	ContentTypeUnknown ContentType = iota
-	ContentTypeMultipart
-	ContentTypeJSON
+var (
+	ErrCommandNotFound = errors.New("command not registered")
+	ErrInvalidCommand  = errors.New("invalid command")
 )
 
 type ServerConfig struct {
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
 }
 
 type Config struct {
-	Bind   string
-	Path   string
-	Server *ServerConfig
+	Bind                         string
+	Path                         string
+	Server                       *ServerConfig
+	BaseServer                   *http.Server
+	BaseContext                  func(listener net.Listener) context.Context
+	ConnContext                  func(ctx context.Context, conn net.Conn) context.Context
+	TLSConfig                    *tls.Config
+	ClientCAs                    *x509.CertPool
+	ShutdownTimeout              time.Duration
+	Logger                       *slog.Logger
+	LogRequests                  bool
+	PanicResponse                Response
+	NotFoundResponse             Response
+	MethodNotAllowedResponse     Response
+	UnsupportedMediaTypeResponse Response
+	InvalidCommandResponse       Response
+	OnPanic                      func(r *Request, recovered any, stack []byte)
+	OnError                      func(r *Request, err error) Response
+	OnDeprecated                 func(r *Request)
+	CacheStore                   CacheStore
+	MaxDecompressedSize          int64
+	EnableIntrospection          bool
+	EnableDocs                   bool
+	StrictDecode                 bool
+	JSONUseNumber                bool
+	Limits                       *DecodeLimits
+	Hooks                        *Hooks
+	ErrorReporter                ErrorReporter
+	Validator                    Validator
+	EnableAsync                  bool
+	JobStore                     JobStore
+	JobLease                     time.Duration
+	AllowGET                     bool
+	Methods                      []string
+	Envelope                     bool
+	CacheControl                 string
+	Authorize                    func(principal Principal, command CommandInfo) error
+	TenantResolver               func(r *http.Request) (string, error)
+	EnableH2C                    bool
+	TrustedProxies               []string
 }
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
 type Command struct {
-	Name string          `json:"name"`
-	Data json.RawMessage `json:"data"`
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data"`
+	Async bool            `json:"async,omitempty"`
+}
+
+type command[T any] struct {
+	handler func(*Request, T) Response
+	group   *Group[T]
+	options commandOptions
 }
 
 type Server[T any] struct {
-	httpServer *http.Server
-	handlers   map[string]func(*Request, T) Response
-	path       string
-	context    T
-	strictMode bool
+	httpServer      *http.Server
+	commands        map[string]*command[T]
+	fallback        *command[T]
+	codecs          map[string]Codec
+	mu              sync.RWMutex
+	path            string
+	context         T
+	factory         func(r *http.Request) (T, error)
+	tenants         map[string]T
+	tenantResolver  func(r *http.Request) (string, error)
+	trustedProxies  []netip.Prefix
+	tracker         *tracker
+	shutdownTimeout time.Duration
+	shutdownHooks   []func(drained int)
+	listeners       []*listener
+	commandChain    []CommandMiddleware[T]
+	before          []func(*Request, T) Response
+	after           []func(*Request, T, Response) Response
+	hooks           *Hooks
+	reporter        ErrorReporter
+	logger          *slog.Logger
+	logRequests     bool
+	envelope        bool
+	enableDocs      bool
+	cacheControl    string
+	panicResponse   Response
+	notFound        Response
+	notAllowed      Response
+	unsupported     Response
+	invalidCommand  Response
+	onError         func(r *Request, err error) Response
+	onDeprecated    func(r *Request)
+	authorizer      func(principal Principal, command CommandInfo) error
+	onPanic         func(*Request, any, []byte)
+	cacheStore      CacheStore
+	allowGET        bool
+	jobStore        JobStore
+	jobLease        time.Duration
+	validator       Validator
+	strictDecode    bool
+	useNumber       bool
+	limits          *DecodeLimits
+	scheduler       context.Context
+	stopScheduler   context.CancelFunc
+	methods         []string
+	maxDecompressed int64
 }
 
+type mountedKey struct{}
+
 func NewServer[T any](config *Config, context T) *Server[T] {
 	if !strings.HasPrefix(config.Path, "/") {
 		config.Path = "/" + config.Path
 	}
 
-	mux := http.NewServeMux()
-
 	httpServer := &http.Server{
 		Addr:         config.Bind,
-		Handler:      mux,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		TLSConfig:    config.TLSConfig,
+	}
+
+	if config.BaseServer != nil {
+		httpServer = config.BaseServer
+
+		if config.Bind != "" {
+			httpServer.Addr = config.Bind
+		}
+
+		if config.TLSConfig != nil {
+			httpServer.TLSConfig = config.TLSConfig
+		}
+	}
+
+	if config.BaseContext != nil {
+		httpServer.BaseContext = config.BaseContext
+	}
+
+	if config.ConnContext != nil {
+		httpServer.ConnContext = config.ConnContext
+	}
+
+	if config.ClientCAs != nil {
+		tlsConfig := httpServer.TLSConfig
+		httpServer.TLSConfig = &tls.Config{}
+
+		if tlsConfig != nil {
+			httpServer.TLSConfig = tlsConfig.Clone()
+		}
+
+		httpServer.TLSConfig.ClientCAs = config.ClientCAs
+		httpServer.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if config.EnableH2C {
+		httpServer.Protocols = &http.Protocols{}
+		httpServer.Protocols.SetHTTP1(true)
+		httpServer.Protocols.SetHTTP2(true)
+		httpServer.Protocols.SetUnencryptedHTTP2(true)
 	}
 
 	if config.Server != nil {
 		httpServer.ReadTimeout = config.Server.ReadTimeout
+		httpServer.ReadHeaderTimeout = config.Server.ReadHeaderTimeout
 		httpServer.WriteTimeout = config.Server.WriteTimeout
+		httpServer.IdleTimeout = config.Server.IdleTimeout
 	}
 
 	server := &Server[T]{
-		httpServer: httpServer,
-		handlers:   make(map[string]func(*Request, T) Response),
-		path:       config.Path,
-		context:    context,
+		httpServer:      httpServer,
+		commands:        make(map[string]*command[T]),
+		codecs:          defaultCodecs(),
+		allowGET:        config.AllowGET,
+		methods:         []string{http.MethodPost},
+		path:            config.Path,
+		context:         context,
+		tracker:         newTracker(),
+		shutdownTimeout: config.ShutdownTimeout,
+		logger:          config.Logger,
+		logRequests:     config.LogRequests,
+		envelope:        config.Envelope,
+		enableDocs:      config.EnableDocs,
+		cacheControl:    config.CacheControl,
+		panicResponse:   config.PanicResponse,
+		notFound:        config.NotFoundResponse,
+		notAllowed:      config.MethodNotAllowedResponse,
+		unsupported:     config.UnsupportedMediaTypeResponse,
+		invalidCommand:  config.InvalidCommandResponse,
+		onPanic:         config.OnPanic,
+		onError:         config.OnError,
+		onDeprecated:    config.OnDeprecated,
+		authorizer:      config.Authorize,
+		tenantResolver:  config.TenantResolver,
+		trustedProxies:  parsePrefixes(config.TrustedProxies),
+		cacheStore:      config.CacheStore,
+		maxDecompressed: config.MaxDecompressedSize,
+		validator:       config.Validator,
+		strictDecode:    config.StrictDecode,
+		useNumber:       config.JSONUseNumber,
+		limits:          config.Limits,
+		hooks:           config.Hooks,
+		reporter:        config.ErrorReporter,
+	}
+
+	if len(config.Methods) > 0 {
+		server.methods = make([]string, len(config.Methods))
+		for i, method := range config.Methods {
+			server.methods[i] = strings.ToUpper(method)
+		}
+	}
+
+	server.scheduler, server.stopScheduler = newScheduler()
+
+	if server.maxDecompressed <= 0 {
+		server.maxDecompressed = 10 << 20
+	}
+
+	if server.cacheStore == nil {
+		server.cacheStore = NewMemoryCache()
+	}
+
+	if server.panicResponse == nil {
+		server.panicResponse = Status(http.StatusInternalServerError)
+	}
+
+	if server.notFound == nil {
+		server.notFound = Status(http.StatusNotFound)
+	}
+
+	if server.notAllowed == nil {
+		server.notAllowed = Status(http.StatusMethodNotAllowed)
+	}
+
+	if server.unsupported == nil {
+		server.unsupported = Status(http.StatusUnsupportedMediaType)
+	}
+
+	if server.invalidCommand == nil {
+		server.invalidCommand = Status(http.StatusUnprocessableEntity)
 	}
 
-	mux.HandleFunc(config.Path, server.withStrictPath()(server.executeHandler))
+	if server.strictDecode {
+		server.codecs["application/json"] = JSONCodec{DisallowUnknownFields: true}
+	}
+
+	if server.validator == nil {
+		server.validator = tagValidator{}
+	}
+
+	if server.logger == nil {
+		server.logger = slog.New(slog.DiscardHandler)
+	}
+
+	server.Use()
+
+	if config.EnableIntrospection {
+		server.Command(introspectCommand, server.introspect)
+	}
+
+	if config.EnableAsync {
+		server.jobStore = config.JobStore
+		if server.jobStore == nil {
+			server.jobStore = NewMemoryJobStore()
+		}
+
+		server.jobLease = config.JobLease
+		if server.jobLease <= 0 {
+			server.jobLease = jobLease
+		}
+
+		server.Command(jobStatusCommand, server.jobStatus)
+		server.Command(jobResultCommand, server.jobResult)
+	}
+
+	return server
+}
+
+func NewServerWithFactory[T any](config *Config, factory func(r *http.Request) (T, error)) *Server[T] {
+	var zero T
+
+	server := NewServer(config, zero)
+	server.factory = factory
 
 	return server
 }
 
 func (s *Server[T]) Use(middleware ...Middleware) {
-	handler := s.executeHandler
-	middleware = append([]Middleware{s.withStrictPath()}, middleware...)
+	commands := append([]Middleware{withResponseInfo, s.withClientIP(), s.withStrictPath(), s.withBodyLimit(), s.withDecompression(), s.withCodec(), s.withListener()}, middleware...)
 
-	for i, j := 0, len(middleware)-1; i < j; i, j = i+1, j-1 {
-		middleware[i], middleware[j] = middleware[j], middleware[i]
-	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(s.path, chain(s.executeHandler, commands))
 
-	for _, mw := range middleware {
-		handler = mw(handler)
+	if s.enableDocs {
+		docs := append([]Middleware{withResponseInfo, s.withClientIP(), s.withListener()}, middleware...)
+		mux.HandleFunc(s.docsPattern(), chain(s.docs, docs))
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc(s.path, handler)
 	s.httpServer.Handler = mux
 }
 
-func (s *Server[T]) Command(name string, handler func(r *Request, context T) Response) {
-	s.handlers[name] = handler
+func chain(handler http.HandlerFunc, middleware []Middleware) http.HandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+func (s *Server[T]) Command(name string, handler func(r *Request, context T) Response, options ...CommandOption) {
+	s.register(name, handler, nil, options)
+}
+
+func (s *Server[T]) Default(handler func(r *Request, context T) Response, options ...CommandOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handler == nil {
+		s.fallback = nil
+		return
+	}
+
+	s.fallback = &command[T]{handler, nil, newCommandOptions(options)}
 }
 
 func (s *Server[T]) Commands(args ...any) error {
-	if s.inferFromHandlers(args...) {
+	return registerCommands(func(name string, handler func(*Request, T) Response) {
+		s.register(name, handler, nil, nil)
+	}, args...)
+}
+
+func (s *Server[T]) CommandMap(commands map[string]func(r *Request, context T) Response, options ...CommandOption) {
+	for name, handler := range commands {
+		s.register(name, handler, nil, options)
+	}
+}
+
+func (s *Server[T]) RemoveCommand(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.commands[name]; !ok {
+		return false
+	}
+
+	delete(s.commands, name)
+	return true
+}
+
+func (s *Server[T]) ReplaceCommand(name string, handler func(r *Request, context T) Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.commands[name]
+	if !ok {
+		return ErrCommandNotFound
+	}
+
+	s.commands[name] = &command[T]{handler, existing.group, existing.options}
+	return nil
+}
+
+func (s *Server[T]) ListCommands() []CommandInfo {
+	names, commands := s.sortedCommands()
+	infos := make([]CommandInfo, 0, len(names))
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		infos = append(infos, commands[i].options.info(name))
+	}
+
+	return infos
+}
+
+func (s *Server[T]) HasCommand(name string) bool {
+	return s.lookup(name) != nil
+}
+
+func (s *Server[T]) Group(name string, middleware ...Middleware) *Group[T] {
+	return &Group[T]{
+		server:     s,
+		prefix:     name,
+		middleware: middleware,
+	}
+}
+
+func (s *Server[T]) HTTPServer() *http.Server {
+	return s.httpServer
+}
+
+func (s *Server[T]) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+func (s *Server[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), mountedKey{}, true)
+	s.httpServer.Handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func (s *Server[T]) Start() error {
+	s.logger.Info("starting server", "addr", s.httpServer.Addr, "path", s.path)
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server[T]) StartContext(ctx context.Context) error {
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- s.Start()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := s.Stop(context.WithoutCancel(ctx)); err != nil {
+		return err
+	}
+
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server[T]) Serve(listener net.Listener) error {
+	s.logger.Info("starting server", "addr", listener.Addr().String(), "path", s.path)
+	return s.httpServer.Serve(listener)
+}
+
+func (s *Server[T]) StartTLS(certFile string, keyFile string) error {
+	s.logger.Info("starting server", "addr", s.httpServer.Addr, "path", s.path, "tls", true)
+	return s.httpServer.ListenAndServeTLS(certFile, keyFile)
+}
+
+func (s *Server[T]) OnShutdown(hook func(drained int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+func (s *Server[T]) Stop(ctx context.Context) error {
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	s.stopScheduler()
+
+	inflight, idle := s.tracker.stop()
+
+	var err error
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	drained := inflight - s.tracker.count()
+	s.logger.Info("stopping server", "inflight", inflight, "drained", drained)
+
+	if shutdownErr := s.httpServer.Shutdown(ctx); err == nil {
+		err = shutdownErr
+	}
+
+	if shutdownErr := s.shutdownListeners(ctx); err == nil {
+		err = shutdownErr
+	}
+
+	s.mu.RLock()
+	hooks := s.shutdownHooks
+	s.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(drained)
+	}
+
+	return err
+}
+
+func (s *Server[T]) register(name string, handler func(*Request, T) Response, group *Group[T], options []CommandOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands[name] = &command[T]{handler, group, newCommandOptions(options)}
+}
+
+func (s *Server[T]) lookup(name string) *command[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.commands[name]
+}
+
+func (s *Server[T]) defaultCommand() *command[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.fallback
+}
+
+func (s *Server[T]) sortedCommands() ([]string, []*command[T]) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.commands))
+	for name := range s.commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	commands := make([]*command[T], len(names))
+	for i, name := range names {
+		commands[i] = s.commands[name]
+	}
+
+	return names, commands
+}
+
+func registerCommands[T any](register func(string, func(*Request, T) Response), args ...any) error {
+	if inferFromHandlers(register, args...) {
 		return nil
 	}
 
 	if len(args) == 1 {
 		handlers, ok := args[0].(map[string]func(*Request, T) Response)
 		if ok {
-			s.handlers = handlers
+			for name, handler := range handlers {
+				register(name, handler)
+			}
+
 			return nil
 		}
 	}
@@ -137,7 +603,7 @@ func (s *Server[T]) Commands(args ...any) error {
 				return errors.New("odd arg must be command handler")
 			}
 
-			s.Command(currentName, handler)
+			register(currentName, handler)
 			currentName = ""
 		}
 	}
@@ -145,120 +611,94 @@ func (s *Server[T]) Commands(args ...any) error {
 	return nil
 }
 
-func (s *Server[T]) Handler() http.Handler {
-	return s.httpServer.Handler
-}
-
-func (s *Server[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.strictMode = true
-	s.httpServer.Handler.ServeHTTP(w, r)
-}
-
-func (s *Server[T]) Start() error {
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server[T]) Stop(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-func (s *Server[T]) inferFromHandlers(args ...any) bool {
-	containsLower := func(str string) bool {
-		matched, _ := regexp.MatchString("[a-z0-9]", str)
-		return matched
+func inferCommandName(name string) string {
+	if matched, _ := regexp.MatchString("[a-z0-9]", name); !matched {
+		return strings.ToLower(name)
 	}
 
-	inferCommandName := func(name string) string {
-		if !containsLower(name) {
-			return strings.ToLower(name)
-		}
-
-		cmd := ""
-
-		for _, char := range name {
-			if unicode.IsUpper(char) || unicode.IsNumber(char) {
-				cmd += " "
-			}
+	cmd := ""
 
-			cmd += string(char)
+	for _, char := range name {
+		if unicode.IsUpper(char) || unicode.IsNumber(char) {
+			cmd += " "
 		}
 
-		return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(cmd), " ", "-"))
+		cmd += string(char)
 	}
 
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(cmd), " ", "-"))
+}
+
+func inferFromHandlers[T any](register func(string, func(*Request, T) Response), args ...any) bool {
 	if len(args) == 0 {
 		return false
 	}
 
+	handlers := make([]func(*Request, T) Response, 0, len(args))
+
 	for _, arg := range args {
 		handler, isHandler := arg.(func(*Request, T) Response)
 		if !isHandler {
 			return false
 		}
 
+		handlers = append(handlers, handler)
+	}
+
+	for _, handler := range handlers {
 		segments := strings.Split(runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(), ".")
 		name := segments[len(segments)-1]
-		s.Command(inferCommandName(name), handler)
+		register(inferCommandName(name), handler)
 	}
 
 	return true
 }
 
-func (s *Server[T]) getContentType(r *http.Request) ContentType {
-	contentTypes := map[string]ContentType{
-		"application/json":    ContentTypeJSON,
-		"multipart/form-data": ContentTypeMultipart,
+func (s *Server[T]) getHandler(r *http.Request, codec Codec) (*command[T], *Command, error) {
+	command, err := s.decodeCommand(r, codec)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	contentType, valid := contentTypes[strings.ToLower(strings.Split(r.Header.Get("Content-Type"), ";")[0])]
-	if !valid {
-		return ContentTypeUnknown
+	if err := s.limits.check(command.Data); err != nil {
+		return nil, nil, err
 	}
 
-	return contentType
-}
-
-func (s *Server[T]) getHandler(r *http.Request, contentType ContentType) (func(*Request, T) Response, *Command, error) {
-	var data []byte
-
-	if contentType == ContentTypeJSON {
-		defer r.Body.Close()
+	handler := s.lookup(command.Name)
+	if handler == nil {
+		return nil, command, nil
+	}
 
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			return nil, nil, err
-		}
+	return handler, command, nil
+}
 
-		data = body
+func (s *Server[T]) decodeCommand(r *http.Request, codec Codec) (*Command, error) {
+	parsed := parsedFrom(r)
+	if parsed == nil {
+		return codec.Decode(r)
 	}
 
-	if contentType == ContentTypeMultipart {
-		body := r.FormValue("command")
-		if body == "" {
-			return nil, nil, errors.New("no JSON payload found in multipart request")
+	if parsed.parsed {
+		if parsed.command == nil && parsed.err == nil {
+			return nil, ErrInvalidCommand
 		}
 
-		data = []byte(body)
-	}
-
-	command := &Command{}
-	if err := json.Unmarshal(data, command); err != nil {
-		return nil, nil, err
+		return parsed.command, parsed.err
 	}
 
-	handler := s.handlers[command.Name]
-	if handler == nil {
-		return nil, nil, nil
-	}
+	command, err := codec.Decode(r)
+	parsed.store(command, err)
 
-	return handler, command, nil
+	return command, err
 }
 
 func (s *Server[T]) withStrictPath() Middleware {
 	return func(h http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			if !s.strictMode && s.path == "/" && r.URL.Path != "/" {
-				w.WriteHeader(http.StatusNotFound)
+			mounted, _ := r.Context().Value(mountedKey{}).(bool)
+
+			if !mounted && s.path == "/" && r.URL.Path != "/" {
+				s.notFound(&responseWriter{w, r, nil})
 				return
 			}
 
@@ -267,32 +707,304 @@ func (s *Server[T]) withStrictPath() Middleware {
 	}
 }
 
+func (s *Server[T]) allowsMethod(method string) bool {
+	if s.allowGET && method == http.MethodGet {
+		return true
+	}
+
+	return slices.Contains(s.methods, method)
+}
+
+func (s *Server[T]) dispatches(r *http.Request) bool {
+	return s.allowsMethod(r.Method)
+}
+
+func (s *Server[T]) allowHeader() string {
+	methods := slices.Clone(s.methods)
+	if s.allowGET && !slices.Contains(methods, http.MethodGet) {
+		methods = append(methods, http.MethodGet)
+	}
+
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
 func (s *Server[T]) executeHandler(w http.ResponseWriter, r *http.Request) {
-	contentType := s.getContentType(r)
-	if contentType == ContentTypeUnknown {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		return
+	if s.cacheControl != "" {
+		writer := &builderWriter{ResponseWriter: w, apply: s.defaultCacheControl}
+		defer writer.finish()
+		w = writer
+	}
+
+	if s.envelope {
+		writer := &envelopeWriter{ResponseWriter: w}
+		defer writer.finish()
+		w = writer
 	}
 
-	if r.Method != "POST" {
-		w.Header().Add("Allow", "POST")
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	if !s.logRequests {
+		s.dispatch(w, r)
 		return
 	}
 
-	handler, command, err := s.getHandler(r, contentType)
+	start := time.Now()
+	recorder := &recordingWriter{ResponseWriter: w}
+	name := s.dispatch(recorder, r)
+
+	s.logger.Info("request", "command", name, "status", recorder.Status(), "size", recorder.size, "duration", time.Since(start))
+}
+
+func (s *Server[T]) defaultCacheControl(header http.Header) {
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", s.cacheControl)
+	}
+}
+
+func (s *Server[T]) dispatch(w http.ResponseWriter, r *http.Request) string {
+	if !s.tracker.begin() {
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return ""
+	}
+
+	defer s.tracker.end()
+
+	codec := s.getCodec(r)
+	if codec == nil {
+		s.unsupported(&responseWriter{w, r, nil})
+		return ""
+	}
+
+	if !s.allowsMethod(r.Method) {
+		w.Header().Add("Allow", s.allowHeader())
+		s.notAllowed(&responseWriter{w, r, nil})
+		return ""
+	}
+
+	handler, command, err := s.getHandler(r, codec)
 	if err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		return
+		s.logger.Warn("failed to decode command", "error", err)
+		traceError(r, err)
+
+		fallback := s.invalidCommand
+		if errors.Is(err, errBodyTooLarge) {
+			fallback = Status(http.StatusRequestEntityTooLarge)
+		}
+
+		err = fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+		s.hooks.decodeError(r, err)
+
+		s.respondError(w, r, nil, err, fallback)
+		return ""
 	}
 
 	if handler == nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
+		handler = s.defaultCommand()
 	}
 
-	responder := handler(&Request{command, w, r}, s.context)
-	if responder != nil {
-		responder(w)
+	if handler == nil {
+		s.logger.Warn("unknown command", "command", command.Name)
+
+		request := &Request{command: *command, RawResponse: w, RawRequest: r}
+		s.respondError(w, r, request, ErrCommandNotFound, s.notFound)
+
+		return command.Name
 	}
+
+	traceCommand(r, command.Name)
+
+	if meta := handler.options.meta; meta.Deprecated {
+		meta.deprecationHeaders(w.Header())
+
+		if s.onDeprecated != nil {
+			s.onDeprecated(&Request{command: *command, RawResponse: w, RawRequest: r})
+		} else {
+			s.logger.Warn("deprecated command called", "command", command.Name)
+		}
+	}
+
+	if !s.authorize(w, r, handler, command) {
+		return command.Name
+	}
+
+	if command.Async && s.jobStore != nil && !strings.HasPrefix(command.Name, "__") {
+		s.submitJob(w, r, handler, command)
+		return command.Name
+	}
+
+	name := command.Name
+	s.runCommand(w, r, handler, command)
+
+	return name
+}
+
+func (s *Server[T]) runCommand(w http.ResponseWriter, r *http.Request, handler *command[T], command *Command) {
+	timeout := handler.options.timeout
+
+	if deadline, ok := requestDeadline(r); ok {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+
+		if timeout == 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	recorder := &recordingWriter{ResponseWriter: w}
+
+	request := &Request{
+		command:     *command,
+		RawResponse: recorder,
+		RawRequest:  r,
+		validator:   s.validator,
+		decoding: decodeOptions{
+			strict:    s.strictDecode || handler.options.strict,
+			useNumber: s.useNumber,
+		},
+	}
+
+	defer func() {
+		parsedFrom(r).release()
+		releaseCommand(command)
+	}()
+
+	if s.hooks != nil {
+		s.hooks.request(request)
+		defer s.hooks.response(request, recorder, time.Now())
+	}
+
+	var failure, panicked error
+
+	if s.reporter != nil {
+		defer func() {
+			err := panicked
+			if err == nil && request.finished.Load() {
+				err = failure
+			}
+
+			s.reportFailure(r, command.Name, recorder, err)
+		}()
+	}
+
+	if breaker := handler.options.breaker; breaker != nil {
+		allowed, retry := breaker.allow(command.Name)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			recorder.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		defer func() {
+			breaker.record(command.Name, recorder.Status() < http.StatusInternalServerError)
+		}()
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicked = s.recover(recorder, request, recovered)
+		}
+	}()
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		defer request.finished.Store(true)
+
+		if progress, ok := r.Context().Value(progressKey{}).(func(int, string)); ok {
+			request.progress = progress
+		} else if acceptsEventStream(r) {
+			stream := &eventStream{ResponseWriter: w}
+			defer stream.finish()
+
+			w = stream
+			request.progress = stream.progress
+		}
+
+		request.RawResponse = w
+		request.RawRequest = r
+
+		if input := handler.options.input; input != nil {
+			data := request.command.Data
+			if len(data) == 0 {
+				data = json.RawMessage("null")
+			}
+
+			value := reflect.New(derefType(input)).Interface()
+
+			if err := decodeData(data, value, request.decoding); err != nil {
+				if request.decoding.strict {
+					Error(http.StatusUnprocessableEntity, err.Error())(&responseWriter{w, r, nil})
+					return
+				}
+			} else if err := s.validator.Validate(value); err != nil {
+				validationFailed(err)(&responseWriter{w, r, nil})
+				return
+			}
+		}
+
+		fail := func(err error) Response {
+			s.logger.Error("command failed", "command", request.command.Name, "error", err)
+			traceError(r, err)
+			failure = err
+
+			if s.onError != nil {
+				return s.onError(request, err)
+			}
+
+			return nil
+		}
+
+		context, err := s.resolveContext(request)
+		if err != nil {
+			s.logger.Error("failed to create context", "command", request.command.Name, "error", err)
+			traceError(r, err)
+			failure = err
+
+			fallback := Status(http.StatusInternalServerError)
+			if errors.Is(err, ErrInvalidTenant) {
+				fallback = Error(http.StatusBadRequest, err.Error())
+			}
+
+			s.respondError(w, r, request, err, fallback)
+			return
+		}
+
+		responder := s.wrapCommand(handler)(request, context)
+		if responder != nil {
+			responder(&responseWriter{w, r, fail})
+		}
+	}
+
+	if timeout > 0 {
+		h = withTimeout(timeout, h)
+	}
+
+	if handler.options.cache > 0 {
+		h = s.withCache(handler.options.cache, request, handler.options.cacheKey, h)
+	}
+
+	handler.group.wrap(h)(recorder, r)
+}
+
+func (s *Server[T]) recover(w *recordingWriter, r *Request, recovered any) error {
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	stack := debug.Stack()
+	s.logger.Error("command panicked", "command", r.GetCommandName(), "panic", recovered, "stack", string(stack))
+
+	if s.onPanic != nil {
+		s.onPanic(r, recovered, stack)
+	}
+
+	s.hooks.panic(r, recovered, stack)
+
+	err := &PanicError{recovered, stack}
+	if w.status == 0 {
+		s.respondError(w, r.RawRequest, r, err, s.panicResponse)
+	}
+
+	return err
 }
@@ -2,18 +2,42 @@ package cadet_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
 	"io"
+	"log/slog"
+	"math/big"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/martinrue/cadet"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func assertEqual(t *testing.T, value any, expected any) {
@@ -581,3 +605,5047 @@ func TestNilResponse(t *testing.T) {
 	assertNoError(t, err)
 	assertEqual(t, resp.StatusCode, http.StatusOK)
 }
+
+func TestGroupCommands(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	admin := server.Group("admin")
+	admin.Command("createUser", func(r *cadet.Request, ctx string) cadet.Response {
+		assertEqual(t, r.GetCommandName(), "admin.createUser")
+		return cadet.Status(http.StatusCreated)
+	})
+
+	admin.Group("users").Commands(
+		"delete", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Status(http.StatusAccepted)
+		},
+	)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"admin.createUser"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusCreated)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"admin.users.delete"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"createUser"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	log := ""
+
+	orderMiddleware := func(value string) cadet.Middleware {
+		return func(h http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				log += value
+				h(w, r)
+			}
+		}
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "", orderMiddleware("1"))
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	admin := server.Group("admin", orderMiddleware("2"))
+	admin.Use(orderMiddleware("3"))
+	admin.Group("users", orderMiddleware("4")).Command("delete", handler)
+	server.Command("public", handler)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"admin.users.delete"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, log, "1234")
+
+	log = ""
+
+	resp, err = req(http.MethodPost, "/", `{"name":"public"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, log, "1")
+}
+
+func TestRemoveCommand(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	assertEqual(t, server.RemoveCommand("cmd"), true)
+	assertEqual(t, server.RemoveCommand("cmd"), false)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestReplaceCommand(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	err := server.ReplaceCommand("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusAccepted)
+	})
+	assertNoError(t, err)
+
+	err = server.ReplaceCommand("missing", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusAccepted)
+	})
+	assertError(t, err)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	group := server.Group("admin")
+	group.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			server.Command("plugin", func(r *cadet.Request, ctx string) cadet.Response {
+				return cadet.Status(http.StatusOK)
+			})
+
+			server.RemoveCommand("plugin")
+
+			server.Before(func(r *cadet.Request, ctx string) cadet.Response {
+				return nil
+			})
+
+			server.After(func(r *cadet.Request, ctx string, response cadet.Response) cadet.Response {
+				return response
+			})
+
+			server.UseCommand(func(next cadet.CommandHandler[string]) cadet.CommandHandler[string] {
+				return next
+			})
+
+			group.UseCommand(func(next cadet.CommandHandler[string]) cadet.CommandHandler[string] {
+				return next
+			})
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for _, name := range []string{"cmd", "admin.cmd"} {
+				resp, err := req(http.MethodPost, "/", `{"name":"`+name+`"}`)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	resp, err := req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+}
+
+func TestStrictPathAfterMounting(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{Path: "/"}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	mounted := httptest.NewRequest(http.MethodPost, "/mounted", strings.NewReader(`{"name":"cmd"}`))
+	mounted.Header.Set("Content-Type", "application/json")
+	server.ServeHTTP(recorder, mounted)
+
+	assertEqual(t, recorder.Code, http.StatusOK)
+
+	resp, err := req(http.MethodPost, "/strict", `{"name":"cmd"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestExportTypeScript(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+
+	type CreateUser struct {
+		Email   string            `json:"email"`
+		Age     int               `json:"age,omitempty"`
+		Tags    []string          `json:"tags"`
+		Address *Address          `json:"address"`
+		Extra   map[string]bool   `json:"extra"`
+		Created time.Time         `json:"created"`
+		Secret  string            `json:"-"`
+		Labels  struct{ A int }   `json:"labels"`
+		Raw     []byte            `json:"raw"`
+		Nested  map[string][]bool `json:"nested-map"`
+	}
+
+	type User struct {
+		ID int64 `json:"id"`
+	}
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("ping", handler)
+	server.Group("admin").Command("create-user", handler, cadet.WithTypes(CreateUser{}, &User{}))
+
+	output := &bytes.Buffer{}
+	assertNoError(t, server.ExportTypeScript(output))
+
+	expected := []string{
+		"export interface Address {\n  city: string;\n}",
+		"export interface CreateUser {\n  email: string;\n  age?: number;\n  tags: string[];\n  address?: Address;\n  extra: Record<string, boolean>;\n  created: string;\n  labels: { A: number; };\n  raw: string;\n  \"nested-map\": Record<string, boolean[]>;\n}",
+		"export interface User {\n  id: number;\n}",
+		`adminCreateUser: (data: CreateUser): Promise<User> => call(url, init, "admin.create-user", data),`,
+		`ping: (data?: unknown): Promise<unknown> => call(url, init, "ping", data),`,
+	}
+
+	for _, snippet := range expected {
+		if !strings.Contains(output.String(), snippet) {
+			t.Fatalf("expected output to contain %q, got:\n%s", snippet, output.String())
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestExportTypeScriptInternal(t *testing.T) {
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server := cadet.NewServer(&cadet.Config{EnableIntrospection: true, EnableAsync: true}, "")
+	server.Command("ping", handler)
+
+	output := &bytes.Buffer{}
+	assertNoError(t, server.ExportTypeScript(output))
+
+	if !strings.Contains(output.String(), `"ping"`) {
+		t.Fatalf("expected output to contain ping, got:\n%s", output.String())
+	}
+
+	if strings.Contains(output.String(), "__") {
+		t.Fatalf("expected no internal commands, got:\n%s", output.String())
+	}
+
+	assertError(t, server.ExportTypeScript(failingWriter{}))
+}
+
+func TestOpenAPI(t *testing.T) {
+	type Echo struct {
+		Text  string `json:"text"`
+		Count int    `json:"count,omitempty"`
+	}
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server := cadet.NewServer(&cadet.Config{Path: "/rpc", EnableIntrospection: true, EnableAsync: true}, "")
+	server.Command("echo", handler, cadet.WithTypes(Echo{}, Echo{}))
+	server.Command("ping", handler)
+
+	data, err := json.Marshal(server.OpenAPI())
+	assertNoError(t, err)
+
+	doc := struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Post struct {
+				RequestBody struct {
+					Content map[string]struct {
+						Schema struct {
+							OneOf []map[string]string `json:"oneOf"`
+						} `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+			} `json:"post"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}{}
+
+	assertNoError(t, json.Unmarshal(data, &doc))
+	assertEqual(t, doc.OpenAPI, "3.0.3")
+
+	oneOf := doc.Paths["/rpc"].Post.RequestBody.Content["application/json"].Schema.OneOf
+	assertEqual(t, len(oneOf), 2)
+	assertEqual(t, oneOf[0]["$ref"], "#/components/schemas/EchoCommand")
+	assertEqual(t, oneOf[1]["$ref"], "#/components/schemas/PingCommand")
+
+	echo := doc.Components.Schemas["EchoCommand"]
+	assertEqual(t, string(echo.Properties["data"]), `{"$ref":"#/components/schemas/Echo"}`)
+
+	schema := doc.Components.Schemas["Echo"]
+	assertEqual(t, string(schema.Properties["count"]), `{"type":"integer"}`)
+	assertEqual(t, len(schema.Required), 1)
+	assertEqual(t, schema.Required[0], "text")
+}
+
+func TestIntrospection(t *testing.T) {
+	type Echo struct {
+		Text string `json:"text"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableIntrospection: true}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithTypes(Echo{}, nil))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"__introspect"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"commands":[{"name":"echo","input":{"$ref":"#/$defs/Echo"}}],"$defs":{"Echo":{"properties":{"text":{"type":"string"}},"required":["text"],"type":"object"}}}`)
+}
+
+func TestIntrospectionDisabled(t *testing.T) {
+	_, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	resp, err := req(http.MethodPost, "/", `{"name":"__introspect"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestStreamResponse(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("stream", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Stream("text/csv", strings.NewReader("a,b\n1,2\n"))
+	})
+
+	server.Command("stream-size", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.StreamSize("application/octet-stream", bytes.NewReader([]byte{1, 2, 3}), 3)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"stream"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.Header.Get("Content-Type"), "text/csv")
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "a,b\n1,2\n")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"stream-size"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.ContentLength, int64(3))
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/octet-stream")
+}
+
+func TestFileResponse(t *testing.T) {
+	path := t.TempDir() + "/report.txt"
+	assertNoError(t, os.WriteFile(path, []byte("0123456789"), 0644))
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("file", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.File(path)
+	})
+
+	server.Command("missing", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.File(path + ".missing")
+	})
+
+	request := func(name string, header http.Header) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+name+`"}`))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		server.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request("file", http.Header{})
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	assertEqual(t, resp.Header().Get("Content-Disposition"), `inline; filename=report.txt`)
+	assertEqual(t, resp.Body.String(), "0123456789")
+
+	resp = request("file", http.Header{"Range": []string{"bytes=2-4"}})
+	assertEqual(t, resp.Code, http.StatusPartialContent)
+	assertEqual(t, resp.Body.String(), "234")
+
+	resp = request("missing", http.Header{})
+	assertEqual(t, resp.Code, http.StatusNotFound)
+}
+
+func TestAttachmentResponse(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Attachment("export", io.MultiReader(strings.NewReader(`{"rows":[]}`)))
+	})
+
+	server.Command("export-csv", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Attachment("my export.csv", strings.NewReader("a,b"))
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"export"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Disposition"), `attachment; filename=export`)
+	assertEqual(t, resp.Header.Get("Content-Type"), "text/plain; charset=utf-8")
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), `{"rows":[]}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"export-csv"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Disposition"), `attachment; filename="my export.csv"`)
+	assertEqual(t, resp.Header.Get("Content-Type"), "text/csv; charset=utf-8")
+}
+
+func TestServerTimeouts(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{
+		Bind: "127.0.0.1:9501",
+		Server: &cadet.ServerConfig{
+			ReadHeaderTimeout: 50 * time.Millisecond,
+			IdleTimeout:       50 * time.Millisecond,
+		},
+	}, "")
+
+	go server.Start()
+	defer server.Stop(context.Background())
+
+	var conn net.Conn
+	var err error
+
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", "127.0.0.1:9501"); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertNoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\n"))
+	assertNoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = io.ReadAll(conn)
+
+	assertNoError(t, err)
+}
+
+func TestStartTLS(t *testing.T) {
+	certificates := httptest.NewUnstartedServer(nil)
+	certificates.StartTLS()
+	defer certificates.Close()
+
+	server := cadet.NewServer(&cadet.Config{
+		Bind:      "127.0.0.1:9502",
+		TLSConfig: &tls.Config{Certificates: certificates.TLS.Certificates},
+	}, "")
+
+	server.Command("secure", func(r *cadet.Request, ctx string) cadet.Response {
+		assertEqual(t, r.RawRequest.TLS != nil, true)
+		return cadet.Status(http.StatusOK)
+	})
+
+	go server.StartTLS("", "")
+	defer server.Stop(context.Background())
+
+	var resp *http.Response
+	var err error
+
+	for i := 0; i < 50; i++ {
+		resp, err = certificates.Client().Post("https://127.0.0.1:9502", "application/json", strings.NewReader(`{"name":"secure"}`))
+		if err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+}
+
+func createClientCertificate(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assertNoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assertNoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	assertNoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMutualTLS(t *testing.T) {
+	certificates := httptest.NewUnstartedServer(nil)
+	certificates.StartTLS()
+	defer certificates.Close()
+
+	trusted := createClientCertificate(t, "billing-service")
+	untrusted := createClientCertificate(t, "intruder")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(trusted.Leaf)
+
+	server := cadet.NewServer(&cadet.Config{
+		Bind:      "127.0.0.1:9503",
+		TLSConfig: &tls.Config{Certificates: certificates.TLS.Certificates},
+		ClientCAs: clientCAs,
+	}, "")
+
+	server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.ClientCertificate().Subject.CommonName)
+	})
+
+	go server.StartTLS("", "")
+	defer server.Stop(context.Background())
+
+	client := func(certificate tls.Certificate) *http.Client {
+		transport := certificates.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+		return &http.Client{Transport: transport}
+	}
+
+	var resp *http.Response
+	var err error
+
+	for i := 0; i < 50; i++ {
+		resp, err = client(trusted).Post("https://127.0.0.1:9503", "application/json", strings.NewReader(`{"name":"whoami"}`))
+		if err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "billing-service")
+
+	_, err = client(untrusted).Post("https://127.0.0.1:9503", "application/json", strings.NewReader(`{"name":"whoami"}`))
+	assertError(t, err)
+}
+
+func TestServeListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusAccepted)
+	})
+
+	go server.Serve(listener)
+	defer server.Stop(context.Background())
+
+	resp, err := http.Post("http://"+listener.Addr().String(), "application/json", strings.NewReader(`{"name":"cmd"}`))
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+}
+
+func TestGracefulStop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	url := "http://" + listener.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		close(started)
+		<-release
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Command("fast", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	drained := make(chan int, 1)
+	server.OnShutdown(func(count int) {
+		drained <- count
+	})
+
+	go server.Serve(listener)
+
+	slow := make(chan *http.Response)
+	go func() {
+		resp, _ := http.Post(url, "application/json", strings.NewReader(`{"name":"slow"}`))
+		slow <- resp
+	}()
+
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Stop(context.Background())
+	}()
+
+	for {
+		resp, err := http.Post(url, "application/json", strings.NewReader(`{"name":"fast"}`))
+		assertNoError(t, err)
+
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+
+	resp := <-slow
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertNoError(t, <-stopped)
+	assertEqual(t, <-drained, 1)
+}
+
+func TestStopTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	server := cadet.NewServer(&cadet.Config{ShutdownTimeout: 20 * time.Millisecond}, "")
+	server.Command("stuck", func(r *cadet.Request, ctx string) cadet.Response {
+		close(started)
+		<-release
+		return cadet.Status(http.StatusOK)
+	})
+
+	drained := -1
+	server.OnShutdown(func(count int) {
+		drained = count
+	})
+
+	go server.Serve(listener)
+	go http.Post("http://"+listener.Addr().String(), "application/json", strings.NewReader(`{"name":"stuck"}`))
+
+	<-started
+
+	err = server.Stop(context.Background())
+	assertEqual(t, err, context.DeadlineExceeded)
+	assertEqual(t, drained, 0)
+}
+
+func TestStartContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	addr := listener.Addr().String()
+
+	server := cadet.NewServer(&cadet.Config{Bind: addr}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	assertError(t, server.StartContext(context.Background()))
+	listener.Close()
+
+	stopped := make(chan struct{})
+	server.OnShutdown(func(count int) {
+		close(stopped)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- server.StartContext(ctx)
+	}()
+
+	for {
+		resp, err := http.Post("http://"+addr, "application/json", strings.NewReader(`{"name":"ping"}`))
+		if err == nil {
+			assertEqual(t, resp.StatusCode, http.StatusOK)
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	assertNoError(t, <-done)
+	<-stopped
+}
+
+func TestAddListener(t *testing.T) {
+	public, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Group("admin").Command("reset", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.AddListener(public, cadet.DenyCommands("admin.*"))
+	server.AddListener(admin, cadet.AllowCommands("admin.*"))
+
+	for _, test := range []struct {
+		listener net.Listener
+		command  string
+		status   int
+	}{
+		{public, "ping", http.StatusOK},
+		{public, "admin.reset", http.StatusNotFound},
+		{admin, "ping", http.StatusNotFound},
+		{admin, "admin.reset", http.StatusOK},
+	} {
+		url := "http://" + test.listener.Addr().String()
+
+		resp, err := http.Post(url, "application/json", strings.NewReader(`{"name":"`+test.command+`"}`))
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+	}
+
+	assertNoError(t, server.Stop(context.Background()))
+
+	_, err = http.Post("http://"+admin.Addr().String(), "application/json", strings.NewReader(`{"name":"admin.reset"}`))
+	assertError(t, err)
+}
+
+func TestH2C(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	url := "http://" + listener.Addr().String()
+
+	server := cadet.NewServer(&cadet.Config{EnableH2C: true}, "")
+	server.Command("proto", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.RawRequest.Proto)
+	})
+
+	go server.Serve(listener)
+	defer server.Stop(context.Background())
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+
+	for _, test := range []struct {
+		client *http.Client
+		proto  string
+	}{
+		{&http.Client{Transport: &http.Transport{Protocols: protocols}}, "HTTP/2.0"},
+		{http.DefaultClient, "HTTP/1.1"},
+	} {
+		resp, err := test.client.Post(url, "application/json", strings.NewReader(`{"name":"proto"}`))
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, resp.Proto, test.proto)
+		assertEqual(t, string(data), test.proto)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == "duration" {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	}))
+
+	server, req := createJSONRequest(t, &cadet.Config{Logger: logger, LogRequests: true}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusAccepted)
+	})
+
+	_, err := req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+
+	_, err = req(http.MethodPost, "/", `invalid`)
+	assertNoError(t, err)
+
+	_, err = req(http.MethodPost, "/", `{"name":"unknown"}`)
+	assertNoError(t, err)
+
+	expected := []string{
+		`level=INFO msg=request command=cmd status=202 size=0`,
+		`level=WARN msg="failed to decode command" error="invalid character 'i' looking for beginning of value"`,
+		`level=INFO msg=request command="" status=422 size=0`,
+		`level=WARN msg="unknown command" command=unknown`,
+		`level=INFO msg=request command=unknown status=404 size=0`,
+	}
+
+	assertEqual(t, strings.TrimSpace(output.String()), strings.Join(expected, "\n"))
+}
+
+func TestAccessLog(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == "duration" || attr.Key == "remote" {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	}))
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "", cadet.AccessLog(logger))
+	server.Command("text", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("hello")
+	})
+
+	_, err := req(http.MethodPost, "/", `{"name":"text"}`)
+	assertNoError(t, err)
+
+	_, err = req(http.MethodGet, "/", `{"name":"text"}`)
+	assertNoError(t, err)
+
+	expected := []string{
+		`level=INFO msg=access method=POST path=/ status=200 size=5`,
+		`level=INFO msg=access method=GET path=/ status=405 size=0`,
+	}
+
+	assertEqual(t, strings.TrimSpace(output.String()), strings.Join(expected, "\n"))
+}
+
+func TestRequestID(t *testing.T) {
+	ids := make(chan string, 2)
+
+	server, _ := createJSONRequest(t, &cadet.Config{}, "", cadet.RequestID())
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		ids <- r.RequestID()
+		return cadet.Status(http.StatusOK)
+	})
+
+	request := func(header http.Header) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"cmd"}`))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request(http.Header{"X-Request-Id": []string{"abc-123"}})
+	assertEqual(t, resp.Header().Get("X-Request-ID"), "abc-123")
+	assertEqual(t, <-ids, "abc-123")
+
+	resp = request(http.Header{})
+	generated := resp.Header().Get("X-Request-ID")
+	assertEqual(t, len(generated), 32)
+	assertEqual(t, <-ids, generated)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("panic", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"panic"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusInternalServerError)
+}
+
+func TestPanicHook(t *testing.T) {
+	type report struct {
+		command   string
+		recovered any
+		stack     string
+	}
+
+	reports := make(chan report, 1)
+
+	server, req := createJSONRequest(t, &cadet.Config{
+		PanicResponse: cadet.Error(http.StatusInternalServerError, "something went wrong"),
+		OnPanic: func(r *cadet.Request, recovered any, stack []byte) {
+			reports <- report{r.GetCommandName(), recovered, string(stack)}
+		},
+	}, "")
+
+	server.Command("panic", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"panic"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusInternalServerError)
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"something went wrong"}`)
+
+	panicReport := <-reports
+	assertEqual(t, panicReport.command, "panic")
+	assertEqual(t, panicReport.recovered, "boom")
+	assertEqual(t, strings.Contains(panicReport.stack, "TestPanicHook"), true)
+}
+
+func TestRateLimit(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "", cadet.RateLimit(cadet.RateLimitOptions{
+		Limit: cadet.Limit{Rate: 0.5, Burst: 2},
+		Commands: map[string]cadet.Limit{
+			"expensive": {Rate: 0.1, Burst: 1},
+			"unlimited": {},
+		},
+	}))
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server.Commands("cheap", handler, "expensive", handler, "unlimited", handler)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"expensive"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"expensive"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusTooManyRequests)
+	assertEqual(t, resp.Header.Get("Retry-After"), "10")
+
+	for i := 0; i < 2; i++ {
+		resp, err = req(http.MethodPost, "/", `{"name":"cheap"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = req(http.MethodPost, "/", `{"name":"cheap"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusTooManyRequests)
+	assertEqual(t, resp.Header.Get("Retry-After"), "2")
+
+	for i := 0; i < 5; i++ {
+		resp, err = req(http.MethodPost, "/", `{"name":"unlimited"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestRateLimitMultipart(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.RateLimit(cadet.RateLimitOptions{
+		Commands: map[string]cadet.Limit{"upload": {Rate: 1, Burst: 1}},
+	}))
+
+	server.Command("upload", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	upload := func() int {
+		buffer := &bytes.Buffer{}
+		mw := multipart.NewWriter(buffer)
+		mw.WriteField("command", `{"name":"upload"}`)
+		mw.Close()
+
+		resp, err := http.Post(httpServer.URL, mw.FormDataContentType(), buffer)
+		assertNoError(t, err)
+		return resp.StatusCode
+	}
+
+	assertEqual(t, upload(), http.StatusOK)
+	assertEqual(t, upload(), http.StatusTooManyRequests)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failing := true
+	transitions := []string{}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("flaky", func(r *cadet.Request, ctx string) cadet.Response {
+		if failing {
+			return cadet.Status(http.StatusInternalServerError)
+		}
+
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithCircuitBreaker(cadet.BreakerOptions{
+		Threshold: 2,
+		Cooldown:  50 * time.Millisecond,
+		OnStateChange: func(command string, from, to cadet.BreakerState) {
+			transitions = append(transitions, command+":"+from.String()+"->"+to.String())
+		},
+	}))
+
+	statuses := func(count int) string {
+		result := []string{}
+
+		for i := 0; i < count; i++ {
+			resp, err := req(http.MethodPost, "/", `{"name":"flaky"}`)
+			assertNoError(t, err)
+			result = append(result, strconv.Itoa(resp.StatusCode))
+		}
+
+		return strings.Join(result, ",")
+	}
+
+	assertEqual(t, statuses(3), "500,500,503")
+
+	time.Sleep(60 * time.Millisecond)
+	assertEqual(t, statuses(2), "500,503")
+
+	time.Sleep(60 * time.Millisecond)
+	failing = false
+	assertEqual(t, statuses(2), "200,200")
+
+	assertEqual(t, strings.Join(transitions, " "), "flaky:closed->open flaky:open->half-open flaky:half-open->open flaky:open->half-open flaky:half-open->closed")
+}
+
+func TestCommandTimeout(t *testing.T) {
+	canceled := make(chan bool, 1)
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		select {
+		case <-r.RawRequest.Context().Done():
+			canceled <- true
+		case <-time.After(time.Second):
+			canceled <- false
+		}
+
+		return cadet.Text("too late")
+	}, cadet.WithTimeout(20*time.Millisecond))
+
+	server.Command("fast", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Error(http.StatusConflict, "conflict")
+	}, cadet.WithTimeout(time.Second))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"slow"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusGatewayTimeout)
+	assertEqual(t, <-canceled, true)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"fast"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusConflict)
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/json; charset=utf-8")
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"conflict"}`)
+}
+
+func TestCommandTimeoutPanic(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("panic", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	}, cadet.WithTimeout(time.Second))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"panic"}`)
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusInternalServerError)
+}
+
+func TestCommandCache(t *testing.T) {
+	calls := 0
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "", cadet.RequestID())
+	server.Command("square", func(r *cadet.Request, ctx string) cadet.Response {
+		calls++
+
+		data := &struct {
+			N int `json:"n"`
+		}{}
+
+		if err := r.ReadCommand(data); err != nil || data.N < 0 {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.JSON(data.N * data.N)
+	}, cadet.WithCache(time.Minute))
+
+	call := func(body string) (int, string, string) {
+		resp, err := req(http.MethodPost, "/", body)
+		assertNoError(t, err)
+
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+
+		return resp.StatusCode, strings.TrimSpace(string(data)), resp.Header.Get("X-Request-ID")
+	}
+
+	status, body, id1 := call(`{"name":"square","data":{"n":3}}`)
+	assertEqual(t, status, http.StatusOK)
+	assertEqual(t, body, "9")
+
+	status, body, id2 := call(`{"name":"square","data": { "n": 3 }}`)
+	assertEqual(t, status, http.StatusOK)
+	assertEqual(t, body, "9")
+	assertEqual(t, calls, 1)
+	assertEqual(t, id1 != id2, true)
+
+	status, body, _ = call(`{"name":"square","data":{"n":4}}`)
+	assertEqual(t, body, "16")
+	assertEqual(t, calls, 2)
+
+	call(`{"name":"square","data":{"n":-1}}`)
+	status, _, _ = call(`{"name":"square","data":{"n":-1}}`)
+	assertEqual(t, status, http.StatusUnprocessableEntity)
+	assertEqual(t, calls, 4)
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := cadet.NewMemoryCache()
+	cache.Set(context.Background(), "key", &cadet.CachedResponse{Status: http.StatusOK}, 10*time.Millisecond)
+
+	_, ok := cache.Get(context.Background(), "key")
+	assertEqual(t, ok, true)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok = cache.Get(context.Background(), "key")
+	assertEqual(t, ok, false)
+}
+
+func TestGzipRequest(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{MaxDecompressedSize: 64}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		data := ""
+		assertNoError(t, r.ReadCommand(&data))
+		return cadet.Text(data)
+	})
+
+	compress := func(body string) io.Reader {
+		buffer := &bytes.Buffer{}
+		writer := gzip.NewWriter(buffer)
+		writer.Write([]byte(body))
+		writer.Close()
+		return buffer
+	}
+
+	request := func(body io.Reader, encoding string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request(compress(`{"name":"echo","data":"compressed"}`), "gzip")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Body.String(), "compressed")
+
+	resp = request(compress(`{"name":"echo","data":"`+strings.Repeat("a", 64)+`"}`), "gzip")
+	assertEqual(t, resp.Code, http.StatusRequestEntityTooLarge)
+
+	resp = request(strings.NewReader(`{"name":"echo"}`), "gzip")
+	assertEqual(t, resp.Code, http.StatusUnprocessableEntity)
+
+	resp = request(strings.NewReader(`{"name":"echo"}`), "br")
+	assertEqual(t, resp.Code, http.StatusUnsupportedMediaType)
+}
+
+func TestMsgPack(t *testing.T) {
+	type Point struct {
+		X     int64  `json:"x"`
+		Y     int64  `json:"y"`
+		Label string `json:"label"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("move", func(r *cadet.Request, ctx string) cadet.Response {
+		point := &Point{}
+		if err := r.ReadCommand(point); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.MsgPack(&Point{point.X + 1, point.Y + 1, point.Label})
+	})
+
+	body, err := msgpack.Marshal(map[string]any{
+		"name": "move",
+		"data": map[string]any{"x": 1, "y": 9007199254740993, "label": "a"},
+	})
+	assertNoError(t, err)
+
+	resp, err := req(http.MethodPost, "/", string(body), "application/msgpack")
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/msgpack")
+
+	defer resp.Body.Close()
+
+	result := map[string]any{}
+	assertNoError(t, msgpack.NewDecoder(resp.Body).Decode(&result))
+	assertEqual(t, fmt.Sprint(result["x"]), "2")
+	assertEqual(t, fmt.Sprint(result["y"]), "9007199254740994")
+	assertEqual(t, result["label"], "a")
+
+	resp, err = req(http.MethodPost, "/", "invalid", "application/msgpack")
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+}
+
+func TestRequestReadProto(t *testing.T) {
+	received := make(chan *structpb.Struct, 1)
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("proto", func(r *cadet.Request, ctx string) cadet.Response {
+		msg := &structpb.Struct{}
+		if err := r.ReadProto(msg); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		received <- msg
+		return cadet.Status(http.StatusOK)
+	})
+
+	msg, err := structpb.NewStruct(map[string]any{"email": "me@home.com"})
+	assertNoError(t, err)
+
+	data, err := proto.Marshal(msg)
+	assertNoError(t, err)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"proto","data":"`+base64.StdEncoding.EncodeToString(data)+`"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, (<-received).Fields["email"].GetStringValue(), "me@home.com")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"proto","data":{"email":"you@home.com"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, (<-received).Fields["email"].GetStringValue(), "you@home.com")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"proto","data":"not base64!"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+}
+
+type lineCodec struct{}
+
+func (lineCodec) Decode(r *http.Request) (*cadet.Command, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	name, data, _ := strings.Cut(strings.TrimSpace(string(body)), " ")
+	return &cadet.Command{Name: name, Data: json.RawMessage(strconv.Quote(data))}, nil
+}
+
+func (lineCodec) Encode(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "text/x-command")
+	_, err := fmt.Fprint(w, v)
+	return err
+}
+
+func TestRegisterCodec(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.RegisterCodec("text/x-command", lineCodec{})
+	server.RegisterCodec("application/vnd.api+json", cadet.JSONCodec{})
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		text := ""
+		if err := r.ReadCommand(&text); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Text(text)
+	})
+
+	resp, err := req(http.MethodPost, "/", "echo hello world", "text/x-command; charset=utf-8")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "hello world")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"echo","data":"hi"}`, "application/vnd.api+json")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	data, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "hi")
+
+	resp, err = req(http.MethodPost, "/", "echo hello", "text/plain")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnsupportedMediaType)
+}
+
+func TestContentNegotiation(t *testing.T) {
+	type Point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+
+	server, _ := createJSONRequest(t, &cadet.Config{}, "")
+	server.RegisterCodec("application/vnd.api+json", cadet.JSONCodec{})
+	server.Command("point", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Body(&Point{1, 2})
+	})
+
+	request := func(accept string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"point"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request("")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	assertEqual(t, resp.Header().Get("Vary"), "Accept")
+	assertEqual(t, strings.TrimSpace(resp.Body.String()), `{"x":1,"y":2}`)
+
+	resp = request("application/msgpack")
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/msgpack")
+
+	decoder := msgpack.NewDecoder(resp.Body)
+	decoder.SetCustomStructTag("json")
+
+	point := &Point{}
+	assertNoError(t, decoder.Decode(point))
+	assertEqual(t, *point, Point{1, 2})
+
+	resp = request("application/json;q=0.5, application/cbor")
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/cbor")
+
+	point = &Point{}
+	assertNoError(t, cbor.Unmarshal(resp.Body.Bytes(), point))
+	assertEqual(t, *point, Point{1, 2})
+
+	resp = request("text/html, application/vnd.api+json;q=0.9")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+
+	resp = request("text/html, */*;q=0.1")
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+
+	resp = request("text/html, application/json;q=0")
+	assertEqual(t, resp.Code, http.StatusNotAcceptable)
+}
+
+func TestCBORRequest(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		data := map[string]any{}
+		if err := r.ReadCommand(&data); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.CBOR(data)
+	})
+
+	body, err := cbor.Marshal(map[string]any{"name": "echo", "data": map[string]any{"text": "hi"}})
+	assertNoError(t, err)
+
+	resp, err := req(http.MethodPost, "/", string(body), "application/cbor")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/cbor")
+
+	defer resp.Body.Close()
+
+	result := map[string]any{}
+	assertNoError(t, cbor.NewDecoder(resp.Body).Decode(&result))
+	assertEqual(t, result["text"], "hi")
+}
+
+func TestFormRequest(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("greet", func(r *cadet.Request, ctx string) cadet.Response {
+		data := &struct {
+			Name string `json:"name"`
+		}{}
+
+		if err := r.ReadCommand(data); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Text("hello " + data.Name)
+	})
+
+	form := url.Values{"name": {"greet"}, "data": {`{"name":"cadet"}`}}
+
+	resp, err := req(http.MethodPost, "/", form.Encode(), "application/x-www-form-urlencoded")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "hello cadet")
+
+	form = url.Values{"name": {"greet"}, "data": {"not json"}}
+
+	resp, err = req(http.MethodPost, "/", form.Encode(), "application/x-www-form-urlencoded")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	resp, err = req(http.MethodPost, "/", "data=%7B%7D", "application/x-www-form-urlencoded")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+}
+
+func TestAllowGET(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{AllowGET: true}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		text := ""
+		r.ReadCommand(&text)
+		return cadet.Text(r.RawRequest.Method + " " + text)
+	})
+
+	query := url.Values{"name": {"echo"}, "data": {`"hello"`}}
+
+	resp, err := req(http.MethodGet, "/?"+query.Encode(), "", "")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "GET hello")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"echo","data":"world"}`)
+	assertNoError(t, err)
+
+	data, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "POST world")
+
+	resp, err = req(http.MethodGet, "/?name=echo&data=invalid", "", "")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	resp, err = req(http.MethodGet, "/?name=missing", "", "")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+
+	resp, err = req(http.MethodPut, "/", `{"name":"echo"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusMethodNotAllowed)
+	assertEqual(t, resp.Header.Get("Allow"), "GET, POST")
+
+	_, req = createJSONRequest(t, &cadet.Config{}, "")
+
+	resp, err = req(http.MethodGet, "/?"+query.Encode(), "", "")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnsupportedMediaType)
+}
+
+func TestConfigMethods(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{Methods: []string{"put", "PATCH"}}, "")
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.RawRequest.Method)
+	})
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		resp, err := req(method, "/", `{"name":"cmd"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), method)
+	}
+
+	resp, err := req(http.MethodPost, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusMethodNotAllowed)
+	assertEqual(t, resp.Header.Get("Allow"), "PATCH, PUT")
+
+	_, req = createJSONRequest(t, &cadet.Config{Methods: []string{"POST"}, AllowGET: true}, "")
+
+	resp, err = req(http.MethodDelete, "/", `{"name":"cmd"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusMethodNotAllowed)
+	assertEqual(t, resp.Header.Get("Allow"), "GET, POST")
+}
+
+func TestInvoke(t *testing.T) {
+	type Sum struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+
+	server, _ := createJSONRequest(t, &cadet.Config{Path: "/api"}, "ctx", func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "yes")
+			next(w, r)
+		}
+	})
+
+	server.Command("sum", func(r *cadet.Request, ctx string) cadet.Response {
+		sum := &Sum{}
+		if err := r.ReadCommand(sum); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.JSON(map[string]any{"total": sum.A + sum.B, "ctx": ctx})
+	})
+
+	result, err := server.Invoke(context.Background(), "sum", &Sum{2, 3})
+	assertNoError(t, err)
+	assertEqual(t, result.Status, http.StatusOK)
+	assertEqual(t, result.Header.Get("X-Middleware"), "yes")
+
+	output := &struct {
+		Total int    `json:"total"`
+		Ctx   string `json:"ctx"`
+	}{}
+
+	assertNoError(t, result.Decode(output))
+	assertEqual(t, output.Total, 5)
+	assertEqual(t, output.Ctx, "ctx")
+
+	result, err = server.Invoke(context.Background(), "sum", []int{1})
+	assertNoError(t, err)
+	assertEqual(t, result.Status, http.StatusUnprocessableEntity)
+
+	_, err = server.Invoke(context.Background(), "missing", nil)
+	assertEqual(t, err, cadet.ErrCommandNotFound)
+
+	_, err = server.Invoke(context.Background(), "sum", make(chan int))
+	assertError(t, err)
+}
+
+func TestAsyncCommand(t *testing.T) {
+	release := make(chan struct{})
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		<-release
+		return cadet.JSON(map[string]string{"url": "/exports/1.csv"})
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"export","async":true}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+
+	job := &cadet.Job{}
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(job))
+	resp.Body.Close()
+
+	assertEqual(t, job.Command, "export")
+	assertEqual(t, len(job.ID), 32)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"__job_result","data":{"id":"`+job.ID+`"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+	resp.Body.Close()
+
+	close(release)
+
+	for job.Status != cadet.JobCompleted {
+		resp, err = req(http.MethodPost, "/", `{"name":"__job_status","data":{"id":"`+job.ID+`"}}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(job))
+		resp.Body.Close()
+	}
+
+	resp, err = req(http.MethodPost, "/", `{"name":"__job_result","data":{"id":"`+job.ID+`"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/json; charset=utf-8")
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"url":"/exports/1.csv"}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"__job_status","data":{"id":"missing"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestAsyncDisabled(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("done")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"export","async":true}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"__job_status","data":{"id":"x"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestSchedule(t *testing.T) {
+	runs := make(chan string, 10)
+
+	server, _ := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("rotate-keys", func(r *cadet.Request, ctx string) cadet.Response {
+		key := ""
+		r.ReadCommand(&key)
+		runs <- key
+		return cadet.Status(http.StatusOK)
+	})
+
+	assertError(t, server.Schedule("not a schedule", "rotate-keys", nil))
+	assertNoError(t, server.Schedule("@every 1s", "rotate-keys", "signing"))
+
+	select {
+	case key := <-runs:
+		assertEqual(t, key, "signing")
+	case <-time.After(3 * time.Second):
+		t.Fatal("scheduled command did not run")
+	}
+
+	assertNoError(t, server.Stop(context.Background()))
+
+	select {
+	case <-runs:
+	default:
+	}
+
+	select {
+	case <-runs:
+		t.Fatal("scheduled command ran after stop")
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestResumeJobs(t *testing.T) {
+	store := cadet.NewMemoryJobStore()
+
+	assertNoError(t, store.Save(context.Background(), &cadet.Job{ID: "a", Command: "export", Data: json.RawMessage(`"csv"`), Status: cadet.JobPending}))
+	assertNoError(t, store.Save(context.Background(), &cadet.Job{ID: "b", Command: "removed", Status: cadet.JobPending}))
+	assertNoError(t, store.Save(context.Background(), &cadet.Job{ID: "c", Command: "export", Status: cadet.JobCompleted}))
+
+	claimed, err := store.Claim(context.Background(), "c", time.Minute)
+	assertNoError(t, err)
+	assertEqual(t, claimed, false)
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true, JobStore: store}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		format := ""
+		r.ReadCommand(&format)
+		return cadet.Text("exported " + format)
+	})
+
+	assertNoError(t, server.ResumeJobs(context.Background()))
+
+	for {
+		pending, err := store.List(context.Background(), cadet.JobPending)
+		assertNoError(t, err)
+
+		running, err := store.List(context.Background(), cadet.JobRunning)
+		assertNoError(t, err)
+
+		if len(pending)+len(running) == 0 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := req(http.MethodPost, "/", `{"name":"__job_result","data":{"id":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "exported csv")
+
+	job, ok, err := store.Get(context.Background(), "b")
+	assertNoError(t, err)
+	assertEqual(t, ok, true)
+	assertEqual(t, job.Status, cadet.JobCompleted)
+	assertEqual(t, job.Result.Status, http.StatusNotFound)
+}
+
+func TestResumeRunningJobs(t *testing.T) {
+	store := cadet.NewMemoryJobStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	assertNoError(t, store.Save(ctx, &cadet.Job{ID: "stale", Command: "export", Data: json.RawMessage(`"csv"`), Status: cadet.JobRunning, Progress: 40, Updated: now.Add(-time.Hour)}))
+	assertNoError(t, store.Save(ctx, &cadet.Job{ID: "recent", Command: "export", Data: json.RawMessage(`"pdf"`), Status: cadet.JobRunning, Updated: now}))
+
+	claimed, err := store.Claim(ctx, "recent", time.Minute)
+	assertNoError(t, err)
+	assertEqual(t, claimed, false)
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true, JobStore: store, JobLease: 100 * time.Millisecond}, "")
+
+	var mu sync.Mutex
+	started := map[string]time.Time{}
+
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		format := ""
+		r.ReadCommand(&format)
+
+		mu.Lock()
+		started[format] = time.Now()
+		mu.Unlock()
+
+		return cadet.Text("exported " + format)
+	})
+
+	assertNoError(t, server.ResumeJobs(ctx))
+
+	for _, id := range []string{"stale", "recent"} {
+		deadline := time.Now().Add(2 * time.Second)
+
+		for {
+			job, _, err := store.Get(ctx, id)
+			assertNoError(t, err)
+
+			if job.Status == cadet.JobCompleted {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("job %s still %s", id, job.Status)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		resp, err := req(http.MethodPost, "/", `{"name":"__job_result","data":{"id":"`+id+`"}}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+		resp.Body.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if started["pdf"].Sub(now) < 100*time.Millisecond {
+		t.Errorf("expected recently updated job to wait for its lease, started after %v", started["pdf"].Sub(now))
+	}
+}
+
+type jsonJobStore struct {
+	mu   sync.Mutex
+	jobs map[string][]byte
+}
+
+func (s *jsonJobStore) load(id string) (*cadet.Job, bool) {
+	data, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+
+	job := &cadet.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		panic(err)
+	}
+
+	return job, true
+}
+
+func (s *jsonJobStore) store(job *cadet.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	s.jobs[job.ID] = data
+	return nil
+}
+
+func (s *jsonJobStore) Save(ctx context.Context, job *cadet.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.store(job)
+}
+
+func (s *jsonJobStore) Get(ctx context.Context, id string) (*cadet.Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.load(id)
+	return job, ok, nil
+}
+
+func (s *jsonJobStore) Claim(ctx context.Context, id string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.load(id)
+	if !ok || job.Status == cadet.JobCompleted || (job.Status == cadet.JobRunning && time.Since(job.Updated) <= lease) {
+		return false, nil
+	}
+
+	job.Status = cadet.JobRunning
+	job.Updated = time.Now()
+
+	return true, s.store(job)
+}
+
+func (s *jsonJobStore) Progress(ctx context.Context, id string, percent int, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.load(id)
+	if !ok {
+		return nil
+	}
+
+	job.Progress, job.Message, job.Updated = percent, message, time.Now()
+	return s.store(job)
+}
+
+func (s *jsonJobStore) Complete(ctx context.Context, id string, result *cadet.CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.load(id)
+	if !ok {
+		return nil
+	}
+
+	job.Status, job.Result, job.Updated = cadet.JobCompleted, result, time.Now()
+	return s.store(job)
+}
+
+func (s *jsonJobStore) List(ctx context.Context, status cadet.JobStatus) ([]*cadet.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []*cadet.Job{}
+
+	for id := range s.jobs {
+		if job, _ := s.load(id); job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+func TestSerializedJobStore(t *testing.T) {
+	store := &jsonJobStore{jobs: map[string][]byte{}}
+	ctx := context.Background()
+
+	assertNoError(t, store.Save(ctx, &cadet.Job{ID: "resumed", Command: "export", Data: json.RawMessage(`"pdf"`), Status: cadet.JobPending}))
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true, JobStore: store}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		format := ""
+		r.ReadCommand(&format)
+		return cadet.Text("exported " + format)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"export","data":"csv","async":true}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+
+	var submitted cadet.Job
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(&submitted))
+	resp.Body.Close()
+
+	assertNoError(t, server.ResumeJobs(ctx))
+
+	expected := map[string]string{submitted.ID: "exported csv", "resumed": "exported pdf"}
+
+	for id, body := range expected {
+		deadline := time.Now().Add(2 * time.Second)
+
+		for {
+			job, _, err := store.Get(ctx, id)
+			assertNoError(t, err)
+
+			if job.Status == cadet.JobCompleted {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("job %s still %s", id, job.Status)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		resp, err := req(http.MethodPost, "/", `{"name":"__job_result","data":{"id":"`+id+`"}}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), body)
+
+		resp, err = req(http.MethodPost, "/", `{"name":"__job_status","data":{"id":"`+id+`"}}`)
+		assertNoError(t, err)
+
+		status := map[string]any{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		resp.Body.Close()
+
+		assertEqual(t, status["status"], "completed")
+		assertEqual(t, status["data"], nil)
+		assertEqual(t, status["result"], nil)
+	}
+}
+
+func TestResumeJobsStop(t *testing.T) {
+	store := cadet.NewMemoryJobStore()
+	ctx := context.Background()
+
+	assertNoError(t, store.Save(ctx, &cadet.Job{ID: "recent", Command: "export", Status: cadet.JobRunning, Updated: time.Now()}))
+
+	server := cadet.NewServer(&cadet.Config{EnableAsync: true, JobStore: store, JobLease: 50 * time.Millisecond}, "")
+
+	var runs atomic.Int32
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		runs.Add(1)
+		return cadet.Text("exported")
+	})
+
+	assertNoError(t, server.ResumeJobs(ctx))
+	server.Stop(ctx)
+
+	time.Sleep(150 * time.Millisecond)
+
+	assertEqual(t, runs.Load(), int32(0))
+}
+
+func TestJobHeartbeat(t *testing.T) {
+	store := cadet.NewMemoryJobStore()
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true, JobStore: store, JobLease: 60 * time.Millisecond}, "")
+
+	release := make(chan struct{})
+	runs := make(chan struct{}, 2)
+
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		runs <- struct{}{}
+		<-release
+		return cadet.Text("done")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"slow","async":true}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+
+	var job cadet.Job
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	resp.Body.Close()
+
+	<-runs
+	time.Sleep(200 * time.Millisecond)
+
+	claimed, err := store.Claim(context.Background(), job.ID, 60*time.Millisecond)
+	assertNoError(t, err)
+	assertEqual(t, claimed, false)
+
+	close(release)
+	server.Stop(context.Background())
+
+	assertEqual(t, len(runs), 0)
+}
+func TestProgressEventStream(t *testing.T) {
+	server, _ := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("import", func(r *cadet.Request, ctx string) cadet.Response {
+		r.Progress(50, "halfway")
+		r.Progress(100, "")
+		return cadet.JSON(map[string]int{"rows": 2})
+	})
+
+	request := func(accept string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"import"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request("text/event-stream")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Content-Type"), "text/event-stream")
+	assertEqual(t, resp.Body.String(), "event: progress\ndata: {\"percent\":50,\"message\":\"halfway\"}\n\n"+
+		"event: progress\ndata: {\"percent\":100}\n\n"+
+		"event: result\ndata: {\"rows\":2}\n\n")
+
+	resp = request("application/json")
+	assertEqual(t, resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	assertEqual(t, strings.TrimSpace(resp.Body.String()), `{"rows":2}`)
+}
+
+func TestProgressAsyncJob(t *testing.T) {
+	reported := make(chan struct{})
+	release := make(chan struct{})
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableAsync: true}, "")
+	server.Command("export", func(r *cadet.Request, ctx string) cadet.Response {
+		r.Progress(40, "exporting")
+		close(reported)
+		<-release
+		return cadet.Status(http.StatusOK)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"export","async":true}`)
+	assertNoError(t, err)
+
+	job := &cadet.Job{}
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(job))
+	resp.Body.Close()
+
+	<-reported
+
+	resp, err = req(http.MethodPost, "/", `{"name":"__job_status","data":{"id":"`+job.ID+`"}}`)
+	assertNoError(t, err)
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(job))
+	resp.Body.Close()
+
+	close(release)
+
+	assertEqual(t, job.Progress, 40)
+	assertEqual(t, job.Message, "exporting")
+}
+
+func TestRequestContextCanceled(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+
+	server, _ := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		close(started)
+
+		select {
+		case <-r.Context().Done():
+			canceled <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			canceled <- nil
+		}
+
+		return cadet.Status(http.StatusOK)
+	})
+
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, httpServer.URL, strings.NewReader(`{"name":"slow"}`))
+	assertNoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err = httpServer.Client().Do(req)
+	assertError(t, err)
+	assertEqual(t, <-canceled, context.Canceled)
+}
+
+func TestRequestDeadline(t *testing.T) {
+	server, _ := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+
+		return cadet.Text("done")
+	})
+
+	request := func(key, value string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"slow"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(key, value)
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	start := time.Now()
+	resp := request("X-Request-Deadline", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+	assertEqual(t, resp.Code, http.StatusGatewayTimeout)
+	assertEqual(t, time.Since(start) < 500*time.Millisecond, true)
+
+	resp = request("Grpc-Timeout", "50m")
+	assertEqual(t, resp.Code, http.StatusGatewayTimeout)
+
+	resp = request("X-Request-Deadline", time.Now().Add(-time.Second).Format(time.RFC3339Nano))
+	assertEqual(t, resp.Code, http.StatusGatewayTimeout)
+
+	resp = request("X-Request-Deadline", "invalid")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Body.String(), "done")
+}
+
+func TestValidation(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	type SignUp struct {
+		Name    string   `json:"name" validate:"required,min=3"`
+		Email   string   `json:"email" validate:"required,email"`
+		Plan    string   `json:"plan" validate:"omitempty,oneof=free pro"`
+		Age     *int     `json:"age,omitempty" validate:"min=18"`
+		Tags    []string `json:"tags" validate:"max=2"`
+		Address *Address `json:"address" validate:"required"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("sign-up", func(r *cadet.Request, ctx string) cadet.Response {
+		err := r.ReadCommand(&SignUp{})
+		if errs, ok := err.(cadet.ValidationErrors); ok {
+			return cadet.Error(http.StatusBadRequest, errs.Error())
+		}
+
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Command("typed-sign-up", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithTypes(SignUp{}, nil))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"sign-up","data":{"name":"ab","email":"nope","plan":"gold","age":16,"tags":["a","b","c"],"address":{}}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"validation failed: address.city is required, age must be at least 18, email must be a valid email address, name must be at least 3 characters, plan must be one of free, pro, tags must be at most 2 items"}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"sign-up","data":{"name":"abc","email":"a@b.com","address":{"city":"Paris"}}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"typed-sign-up","data":{"name":"abc","email":"a@b.com"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+
+	response := &struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}{}
+
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(response))
+	resp.Body.Close()
+
+	assertEqual(t, response.Error, "validation failed")
+	assertEqual(t, len(response.Fields), 1)
+	assertEqual(t, response.Fields["address"], "is required")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"typed-sign-up"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+}
+
+type quotaValidator struct{}
+
+func (quotaValidator) Validate(v any) error {
+	if order, ok := v.(*struct {
+		Quantity int `json:"quantity"`
+	}); ok && order.Quantity > 10 {
+		return errors.New("quantity exceeds quota")
+	}
+
+	return nil
+}
+
+func TestConfigValidator(t *testing.T) {
+	type Order = struct {
+		Quantity int `json:"quantity"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{Validator: quotaValidator{}}, "")
+	server.Command("order", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithTypes(Order{}, nil))
+
+	server.Command("manual-order", func(r *cadet.Request, ctx string) cadet.Response {
+		if err := r.ReadCommand(&Order{}); err != nil {
+			return cadet.Error(http.StatusConflict, err.Error())
+		}
+
+		return cadet.Status(http.StatusOK)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"order","data":{"quantity":11}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"quantity exceeds quota"}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"order","data":{"quantity":1}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"manual-order","data":{"quantity":11}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusConflict)
+}
+
+func TestStrictDecode(t *testing.T) {
+	type Rename struct {
+		Name string `json:"name"`
+	}
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		if err := r.ReadCommand(&Rename{}); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Status(http.StatusOK)
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{StrictDecode: true}, "")
+	server.Command("rename", handler)
+
+	resp, err := req(http.MethodPost, "/", `{"name":"rename","data":{"name":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"nmae":"rename","data":{"name":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"rename","data":{"nmae":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	server, req = createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("rename", handler)
+	server.Command("strict-rename", handler, cadet.WithStrictDecode())
+	server.Command("typed-rename", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithTypes(Rename{}, nil), cadet.WithStrictDecode())
+
+	resp, err = req(http.MethodPost, "/", `{"name":"rename","data":{"nmae":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"strict-rename","data":{"nmae":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"typed-rename","data":{"nmae":"a"}}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusUnprocessableEntity)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"json: unknown field \"nmae\""}`)
+}
+
+func TestJSONUseNumber(t *testing.T) {
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		data := map[string]any{}
+		if err := r.ReadCommand(&data); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Text(fmt.Sprint(data["id"]))
+	}
+
+	for _, test := range []struct {
+		useNumber bool
+		expected  string
+	}{
+		{true, "9007199254740993"},
+		{false, "9.007199254740992e+15"},
+	} {
+		server, req := createJSONRequest(t, &cadet.Config{JSONUseNumber: test.useNumber}, "")
+		server.Command("lookup", handler)
+
+		resp, err := req(http.MethodPost, "/", `{"name":"lookup","data":{"id":9007199254740993}}`)
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), test.expected)
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{Limits: &cadet.DecodeLimits{MaxDataSize: 64, MaxDepth: 2, MaxArrayLength: 3}}, "")
+	server.Command("store", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	for _, test := range []struct {
+		data   string
+		status int
+	}{
+		{`{"items":[1,2,3]}`, http.StatusOK},
+		{`{"items":[{"a":1},{"b":2}],"names":{"x":"y"}}`, http.StatusUnprocessableEntity},
+		{`{"items":[1,2,3,4]}`, http.StatusUnprocessableEntity},
+		{`{"items":[[1]]}`, http.StatusUnprocessableEntity},
+		{`{"text":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"store","data":`+test.data+`}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{Limits: &cadet.DecodeLimits{MaxBodySize: 64}}, "")
+	server.Use(cadet.RateLimit(cadet.RateLimitOptions{Commands: map[string]cadet.Limit{"other": {Rate: 1, Burst: 1}}}))
+
+	server.Command("store", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	for _, test := range []struct {
+		body    string
+		chunked bool
+		status  int
+	}{
+		{`{"name":"store","data":{"text":"hello"}}`, false, http.StatusOK},
+		{`{"name":"store","data":{"text":"hello"}}`, true, http.StatusOK},
+		{`{"name":"store","data":{"text":"` + strings.Repeat("a", 64) + `"}}`, false, http.StatusRequestEntityTooLarge},
+		{`{"name":"store","data":{"text":"` + strings.Repeat("a", 64) + `"}}`, true, http.StatusRequestEntityTooLarge},
+		{`{"name":"store"} {"name":"store"}`, false, http.StatusUnprocessableEntity},
+		{``, false, http.StatusUnprocessableEntity},
+	} {
+		var body io.Reader = strings.NewReader(test.body)
+		if test.chunked {
+			body = io.MultiReader(body)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+	}
+}
+
+func TestValidationUnknownRules(t *testing.T) {
+	type Order struct {
+		Quantity int    `json:"quantity" validate:"required,gte=1,lte=100"`
+		SKU      string `json:"sku" validate:"required,alphanum,min=abc"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("order", func(r *cadet.Request, ctx string) cadet.Response {
+		var order Order
+		if err := r.ReadCommand(&order); err != nil {
+			return cadet.Error(http.StatusBadRequest, err.Error())
+		}
+
+		return cadet.Text(order.SKU)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"order","data":{"quantity":2,"sku":"A1"}}`)
+	assertNoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, string(data), "A1")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"order","data":{"sku":"A1"}}`)
+	assertNoError(t, err)
+	resp.Body.Close()
+
+	assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+}
+
+func TestValidationErrorResponse(t *testing.T) {
+	type Contact struct {
+		Email string `json:"email" validate:"email"`
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("contact", func(r *cadet.Request, ctx string) cadet.Response {
+		if err := r.ReadCommand(&Contact{}); err != nil {
+			if errs, ok := err.(cadet.ValidationErrors); ok {
+				return cadet.ValidationError(errs)
+			}
+
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.ValidationError(map[string]string{"email": "already registered"})
+	})
+
+	for _, test := range []struct {
+		email    string
+		expected string
+	}{
+		{"nope", `{"error":"validation failed","fields":{"email":"must be a valid email address"}}`},
+		{"a@b.com", `{"error":"validation failed","fields":{"email":"already registered"}}`},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"contact","data":{"email":"`+test.email+`"}}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusBadRequest)
+		assertEqual(t, resp.Header.Get("Content-Type"), "application/json; charset=utf-8")
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), test.expected)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	errs := make(chan error, 1)
+	errPaymentDeclined := errors.New("payment declined")
+
+	config := &cadet.Config{
+		OnError: func(r *cadet.Request, err error) cadet.Response {
+			errs <- err
+
+			var panicErr *cadet.PanicError
+
+			switch {
+			case errors.Is(err, cadet.ErrCommandNotFound):
+				return cadet.Error(http.StatusNotFound, "no such command: "+r.GetCommandName())
+			case errors.Is(err, cadet.ErrInvalidCommand):
+				return cadet.Error(http.StatusUnprocessableEntity, "bad message")
+			case errors.As(err, &panicErr):
+				return cadet.Error(http.StatusInternalServerError, "oops")
+			case errors.Is(err, errPaymentDeclined):
+				return cadet.Error(http.StatusPaymentRequired, err.Error())
+			}
+
+			return nil
+		},
+	}
+
+	server, req := createJSONRequest(t, config, "")
+	server.Command("pay", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Fail(fmt.Errorf("charging card: %w", errPaymentDeclined))
+	})
+
+	server.Command("crash", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	})
+
+	server.Command("broken", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Fail(errors.New("database unavailable"))
+	})
+
+	for _, test := range []struct {
+		body   string
+		status int
+		error  string
+	}{
+		{`{"name":"missing"}`, http.StatusNotFound, `{"error":"no such command: missing"}`},
+		{`{"name":`, http.StatusUnprocessableEntity, `{"error":"bad message"}`},
+		{`{"name":"crash"}`, http.StatusInternalServerError, `{"error":"oops"}`},
+		{`{"name":"pay"}`, http.StatusPaymentRequired, `{"error":"charging card: payment declined"}`},
+		{`{"name":"broken"}`, http.StatusInternalServerError, ``},
+	} {
+		resp, err := req(http.MethodPost, "/", test.body)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), test.error)
+		assertError(t, <-errs)
+	}
+
+	_, req = createJSONRequest(t, &cadet.Config{}, "")
+
+	resp, err := req(http.MethodPost, "/", `{"name":"missing"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestProtocolErrorResponses(t *testing.T) {
+	config := &cadet.Config{
+		NotFoundResponse:             cadet.Error(http.StatusNotFound, "unknown command"),
+		MethodNotAllowedResponse:     cadet.Error(http.StatusMethodNotAllowed, "use POST"),
+		UnsupportedMediaTypeResponse: cadet.Error(http.StatusUnsupportedMediaType, "send json"),
+		InvalidCommandResponse:       cadet.Error(http.StatusUnprocessableEntity, "malformed command"),
+	}
+
+	_, req := createJSONRequest(t, config, "")
+
+	for _, test := range []struct {
+		method      string
+		body        string
+		contentType string
+		status      int
+		error       string
+	}{
+		{http.MethodPost, `{"name":"missing"}`, "application/json", http.StatusNotFound, `{"error":"unknown command"}`},
+		{http.MethodPut, `{"name":"missing"}`, "application/json", http.StatusMethodNotAllowed, `{"error":"use POST"}`},
+		{http.MethodPost, `{"name":"missing"}`, "text/plain", http.StatusUnsupportedMediaType, `{"error":"send json"}`},
+		{http.MethodPost, `{"name":`, "application/json", http.StatusUnprocessableEntity, `{"error":"malformed command"}`},
+	} {
+		resp, err := req(test.method, "/", test.body, test.contentType)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), test.error)
+
+		if test.status == http.StatusMethodNotAllowed {
+			assertEqual(t, resp.Header.Get("Allow"), http.MethodPost)
+		}
+	}
+}
+
+func TestProtocolErrorsBeforeDecoding(t *testing.T) {
+	request := func(config *cadet.Config, encoding string, body string) *httptest.ResponseRecorder {
+		server := cadet.NewServer(config, "")
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	large := `{"name":"cmd","data":"` + strings.Repeat("x", 100) + `"}`
+	limits := &cadet.DecodeLimits{MaxBodySize: 10}
+
+	t.Run("responses", func(t *testing.T) {
+		config := &cadet.Config{
+			UnsupportedMediaTypeResponse: cadet.Error(http.StatusUnsupportedMediaType, "send json"),
+			InvalidCommandResponse:       cadet.Error(http.StatusUnprocessableEntity, "malformed command"),
+			Limits:                       limits,
+		}
+
+		resp := request(config, "br", `{}`)
+		assertEqual(t, resp.Code, http.StatusUnsupportedMediaType)
+		assertEqual(t, strings.TrimSpace(resp.Body.String()), `{"error":"send json"}`)
+
+		config.Limits = nil
+
+		resp = request(config, "gzip", "not gzip")
+		assertEqual(t, resp.Code, http.StatusUnprocessableEntity)
+		assertEqual(t, strings.TrimSpace(resp.Body.String()), `{"error":"malformed command"}`)
+	})
+
+	t.Run("on error", func(t *testing.T) {
+		var errs []error
+
+		config := &cadet.Config{
+			Limits: limits,
+			OnError: func(r *cadet.Request, err error) cadet.Response {
+				errs = append(errs, err)
+				return cadet.Error(http.StatusTeapot, err.Error())
+			},
+		}
+
+		for _, test := range []struct {
+			encoding string
+			body     string
+			target   error
+		}{
+			{"br", `{}`, cadet.ErrUnsupportedEncoding},
+			{"gzip", "not gzip", cadet.ErrInvalidCommand},
+			{"", large, cadet.ErrInvalidCommand},
+		} {
+			errs = nil
+
+			resp := request(config, test.encoding, test.body)
+			assertEqual(t, resp.Code, http.StatusTeapot)
+			assertEqual(t, len(errs), 1)
+			assertEqual(t, errors.Is(errs[0], test.target), true)
+		}
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		for _, test := range []struct {
+			config   *cadet.Config
+			encoding string
+			body     string
+			status   int
+			expected string
+		}{
+			{&cadet.Config{Envelope: true}, "br", `{}`, http.StatusUnsupportedMediaType, `{"ok":false,"error":"Unsupported Media Type"}`},
+			{&cadet.Config{Envelope: true}, "gzip", "not gzip", http.StatusUnprocessableEntity, `{"ok":false,"error":"Unprocessable Entity"}`},
+			{&cadet.Config{Envelope: true, Limits: limits}, "", large, http.StatusRequestEntityTooLarge, `{"ok":false,"error":"Request Entity Too Large"}`},
+		} {
+			resp := request(test.config, test.encoding, test.body)
+			assertEqual(t, resp.Code, test.status)
+			assertEqual(t, strings.TrimSpace(resp.Body.String()), test.expected)
+		}
+	})
+}
+
+func TestEnvelope(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{Envelope: true}, "")
+
+	server.Command("user", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON(map[string]string{"name": "Ada"})
+	})
+
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Command("denied", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Error(http.StatusForbidden, "not allowed")
+	})
+
+	server.Command("invalid", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.ValidationError(cadet.ValidationErrors{"email": "required"})
+	})
+
+	server.Command("text", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("hello")
+	})
+
+	for _, test := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"user", http.StatusOK, `{"ok":true,"data":{"name":"Ada"}}`},
+		{"ping", http.StatusOK, `{"ok":true}`},
+		{"denied", http.StatusForbidden, `{"ok":false,"error":"not allowed"}`},
+		{"invalid", http.StatusBadRequest, `{"ok":false,"error":"validation failed","fields":{"email":"required"}}`},
+		{"missing", http.StatusNotFound, `{"ok":false,"error":"Not Found"}`},
+		{"text", http.StatusOK, `hello`},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), test.body)
+	}
+}
+
+func TestResponseBuilder(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	server.Command("create", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON(map[string]int{"id": 42}).
+			WithStatus(http.StatusCreated).
+			WithHeader("ETag", `"v1"`).
+			WithHeader("Content-Type", "application/vnd.cadet+json").
+			WithCookie(&http.Cookie{Name: "session", Value: "abc"}).
+			WithCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	})
+
+	server.Command("fail", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Fail(errors.New("unavailable")).WithHeader("Retry-After", "30")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"create"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusCreated)
+	assertEqual(t, resp.Header.Get("ETag"), `"v1"`)
+	assertEqual(t, resp.Header.Get("Content-Type"), "application/vnd.cadet+json")
+	assertEqual(t, strings.Join(resp.Header.Values("Set-Cookie"), "; "), "session=abc; theme=dark")
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"id":42}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"fail"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusInternalServerError)
+	assertEqual(t, resp.Header.Get("Retry-After"), "30")
+}
+
+func TestHTMLResponses(t *testing.T) {
+	errs := make(chan error, 1)
+
+	config := &cadet.Config{
+		OnError: func(r *cadet.Request, err error) cadet.Response {
+			errs <- err
+			return nil
+		},
+	}
+
+	server, req := createJSONRequest(t, config, "")
+	tmpl := template.Must(template.New("").Parse(`{{define "greeting"}}<p>Hello, {{.}}!</p>{{end}}`))
+
+	server.Command("snippet", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.HTML("<b>bold</b>")
+	})
+
+	server.Command("greeting", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Template(tmpl, "greeting", "<Ada>")
+	})
+
+	server.Command("missing-template", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Template(tmpl, "farewell", nil)
+	})
+
+	for _, test := range []struct {
+		name string
+		body string
+	}{
+		{"snippet", "<b>bold</b>"},
+		{"greeting", "<p>Hello, &lt;Ada&gt;!</p>"},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, http.StatusOK)
+		assertEqual(t, resp.Header.Get("Content-Type"), "text/html; charset=utf-8")
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), test.body)
+	}
+
+	resp, err := req(http.MethodPost, "/", `{"name":"missing-template"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusInternalServerError)
+	assertError(t, <-errs)
+}
+
+func TestStatusResponses(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	server.Command("delete", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.NoContent()
+	})
+
+	server.Command("create", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Created(map[string]int{"id": 42}, "/users/42")
+	})
+
+	server.Command("import", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Accepted(map[string]string{"job": "abc"})
+	})
+
+	server.Command("touch", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Created(nil, "")
+	})
+
+	for _, test := range []struct {
+		name     string
+		status   int
+		location string
+		body     string
+	}{
+		{"delete", http.StatusNoContent, "", ""},
+		{"create", http.StatusCreated, "/users/42", `{"id":42}`},
+		{"import", http.StatusAccepted, "", `{"job":"abc"}`},
+		{"touch", http.StatusCreated, "", ""},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+		assertEqual(t, resp.Header.Get("Location"), test.location)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), test.body)
+	}
+}
+
+func TestBlobResponse(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	image := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a}
+
+	server.Command("avatar", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Blob("image/png", image)
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"avatar"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Content-Type"), "image/png")
+	assertEqual(t, resp.ContentLength, int64(len(image)))
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, bytes.Equal(data, image), true)
+}
+
+func TestNDJSONResponse(t *testing.T) {
+	errs := make(chan error, 1)
+
+	config := &cadet.Config{
+		OnError: func(r *cadet.Request, err error) cadet.Response {
+			errs <- err
+			return nil
+		},
+	}
+
+	server, req := createJSONRequest(t, config, "")
+
+	server.Command("rows", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.NDJSON(func(stream *cadet.JSONStream) error {
+			for i := 1; i <= 3; i++ {
+				if err := stream.Encode(map[string]int{"id": i}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
+
+	server.Command("broken", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.NDJSON(func(stream *cadet.JSONStream) error {
+			stream.Encode(map[string]int{"id": 1})
+			return errors.New("query failed")
+		})
+	})
+
+	server.Command("interrupted", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.NDJSON(func(stream *cadet.JSONStream) error {
+			stream.Encode(map[string]int{"id": 1})
+			stream.Flush()
+			return errors.New("connection lost")
+		})
+	})
+
+	for _, test := range []struct {
+		name   string
+		status int
+		body   string
+		err    bool
+	}{
+		{"rows", http.StatusOK, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n", false},
+		{"broken", http.StatusInternalServerError, "", true},
+		{"interrupted", http.StatusOK, "{\"id\":1}\n", true},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), test.body)
+
+		if test.status == http.StatusOK {
+			assertEqual(t, resp.Header.Get("Content-Type"), "application/x-ndjson")
+		}
+
+		if test.err {
+			assertError(t, <-errs)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{CacheControl: "no-cache"}, "")
+
+	server.Command("countries", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON([]string{"NZ", "UK"}).WithCache(time.Hour)
+	})
+
+	server.Command("balance", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON(100).WithNoStore()
+	})
+
+	server.Command("profile", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON("Ada")
+	})
+
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return func(w http.ResponseWriter) {}
+	})
+
+	for _, test := range []struct {
+		name         string
+		cacheControl string
+	}{
+		{"countries", "max-age=3600"},
+		{"balance", "no-store"},
+		{"profile", "no-cache"},
+		{"ping", "no-cache"},
+		{"missing", "no-cache"},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.Header.Get("Cache-Control"), test.cacheControl)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	preflight := func(options cadet.CORSOptions, origin string) *httptest.ResponseRecorder {
+		server := cadet.NewServer(&cadet.Config{}, "")
+		server.Use(cadet.CORS(options))
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type, Authorization")
+		server.Handler().ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	for _, test := range []struct {
+		options     cadet.CORSOptions
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{cadet.CORSOptions{}, "https://app.example.com", "*", ""},
+		{cadet.CORSOptions{AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"}}, "https://b.example.com", "https://b.example.com", ""},
+		{cadet.CORSOptions{AllowedOrigins: []string{"https://a.example.com"}}, "https://evil.example.com", "", ""},
+		{cadet.CORSOptions{AllowOriginFunc: func(origin string) bool {
+			return strings.HasSuffix(origin, ".example.com")
+		}}, "https://c.example.com", "https://c.example.com", ""},
+		{cadet.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "https://evil.example", "", ""},
+		{cadet.CORSOptions{AllowCredentials: true}, "https://evil.example", "", ""},
+		{cadet.CORSOptions{AllowedOrigins: []string{"*", "https://app.example.com"}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com", "true"},
+		{cadet.CORSOptions{AllowOriginFunc: func(origin string) bool {
+			return origin == "https://app.example.com"
+		}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com", "true"},
+	} {
+		resp := preflight(test.options, test.origin)
+
+		assertEqual(t, resp.Code, http.StatusNoContent)
+		assertEqual(t, resp.Header().Get("Access-Control-Allow-Origin"), test.allowOrigin)
+		assertEqual(t, resp.Header().Get("Access-Control-Allow-Credentials"), test.credentials)
+		assertEqual(t, resp.Header().Get("Vary"), "Origin")
+
+		if test.allowOrigin != "" {
+			assertEqual(t, resp.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+			assertEqual(t, resp.Header().Get("Access-Control-Allow-Headers"), "Content-Type, Authorization")
+		}
+	}
+
+	resp := preflight(cadet.CORSOptions{AllowedHeaders: []string{"Content-Type"}, MaxAge: time.Hour}, "https://app.example.com")
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+	assertEqual(t, resp.Header().Get("Access-Control-Max-Age"), "3600")
+}
+
+func TestCORSResponse(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.CORS(cadet.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{"X-Request-ID", "ETag"},
+		AllowCredentials: true,
+	}))
+
+	server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON("ok")
+	})
+
+	request := func(origin string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"cmd"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request("https://app.example.com")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Origin"), "https://app.example.com")
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Credentials"), "true")
+	assertEqual(t, resp.Header().Get("Access-Control-Expose-Headers"), "X-Request-ID, ETag")
+	assertEqual(t, resp.Header().Get("Vary"), "Origin")
+
+	resp = request("https://evil.example.com")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Origin"), "")
+	assertEqual(t, resp.Header().Get("Access-Control-Expose-Headers"), "")
+
+	resp = request("")
+	assertEqual(t, resp.Header().Get("Vary"), "")
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Origin"), "")
+
+	server.Use(cadet.CORS(cadet.CORSOptions{AllowCredentials: true}))
+
+	resp = request("https://evil.example")
+	assertEqual(t, resp.Code, http.StatusOK)
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Origin"), "")
+	assertEqual(t, resp.Header().Get("Access-Control-Allow-Credentials"), "")
+}
+
+func TestBearerAuth(t *testing.T) {
+	type user struct {
+		ID string
+	}
+
+	verify := func(token string) (cadet.Principal, error) {
+		if token != "secret" {
+			return nil, errors.New("unknown token")
+		}
+
+		return &user{"u1"}, nil
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.BearerAuth(verify))
+
+	server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.Principal().(*user).ID)
+	})
+
+	for _, test := range []struct {
+		authorization string
+		status        int
+		body          string
+	}{
+		{"Bearer secret", http.StatusOK, "u1"},
+		{"bearer secret", http.StatusOK, "u1"},
+		{"Bearer wrong", http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{"Basic c2VjcmV0", http.StatusUnauthorized, `{"error":"missing bearer token"}`},
+		{"", http.StatusUnauthorized, `{"error":"missing bearer token"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"whoami"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", test.authorization)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.TrimSpace(recorder.Body.String()), test.body)
+
+		if test.status == http.StatusUnauthorized {
+			assertEqual(t, recorder.Header().Get("WWW-Authenticate"), "Bearer")
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	revoked := map[string]bool{}
+	keys := cadet.StaticKeys(map[string]cadet.Principal{
+		"key-one": "service-a",
+		"key-two": "service-b",
+	})
+
+	lookup := func(key string) (cadet.Principal, error) {
+		if revoked[key] {
+			return nil, errors.New("revoked")
+		}
+
+		return keys(key)
+	}
+
+	request := func(middleware cadet.Middleware, path string, header string) *httptest.ResponseRecorder {
+		server := cadet.NewServer(&cadet.Config{}, "")
+		server.Use(middleware)
+
+		server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text(r.Principal().(string))
+		})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"name":"whoami"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		if header != "" {
+			req.Header.Set("X-API-Key", header)
+		}
+
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for _, test := range []struct {
+		middleware cadet.Middleware
+		path       string
+		header     string
+		status     int
+		body       string
+	}{
+		{cadet.APIKey("X-API-Key", lookup), "/", "key-one", http.StatusOK, "service-a"},
+		{cadet.APIKey("X-API-Key", lookup), "/", "key-two", http.StatusOK, "service-b"},
+		{cadet.APIKey("X-API-Key", lookup), "/", "key-three", http.StatusUnauthorized, `{"error":"invalid API key"}`},
+		{cadet.APIKey("X-API-Key", lookup), "/", "", http.StatusUnauthorized, `{"error":"missing API key"}`},
+		{cadet.APIKey("X-API-Key", lookup), "/?api_key=key-one", "", http.StatusUnauthorized, `{"error":"missing API key"}`},
+		{cadet.APIKeyQuery("api_key", lookup), "/?api_key=key-one", "", http.StatusOK, "service-a"},
+		{cadet.APIKeyQuery("api_key", lookup), "/", "key-one", http.StatusUnauthorized, `{"error":"missing API key"}`},
+	} {
+		resp := request(test.middleware, test.path, test.header)
+		assertEqual(t, resp.Code, test.status)
+		assertEqual(t, strings.TrimSpace(resp.Body.String()), test.body)
+	}
+
+	revoked["key-one"] = true
+
+	resp := request(cadet.APIKey("X-API-Key", lookup), "/", "key-one")
+	assertEqual(t, resp.Code, http.StatusUnauthorized)
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("signing-secret")
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		assertNoError(t, err)
+		return "Bearer " + token
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.JWT(func(token *jwt.Token) (any, error) {
+		return secret, nil
+	}, cadet.JWTOptions{Methods: []string{"HS256"}, Audience: "cadet", Issuer: "auth"}))
+
+	server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+		subject, err := r.Claims().GetSubject()
+		if err != nil {
+			return cadet.Fail(err)
+		}
+
+		return cadet.Text(subject)
+	})
+
+	valid := jwt.MapClaims{"sub": "u1", "aud": "cadet", "iss": "auth", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := jwt.MapClaims{"sub": "u1", "aud": "cadet", "iss": "auth", "exp": time.Now().Add(-time.Hour).Unix()}
+	noExpiry := jwt.MapClaims{"sub": "u1", "aud": "cadet", "iss": "auth"}
+	wrongAudience := jwt.MapClaims{"sub": "u1", "aud": "other", "iss": "auth", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for _, test := range []struct {
+		authorization string
+		status        int
+		body          string
+	}{
+		{sign(jwt.SigningMethodHS256, secret, valid), http.StatusOK, "u1"},
+		{sign(jwt.SigningMethodHS256, []byte("wrong-secret"), valid), http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{sign(jwt.SigningMethodHS512, secret, valid), http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{sign(jwt.SigningMethodHS256, secret, expired), http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{sign(jwt.SigningMethodHS256, secret, noExpiry), http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{sign(jwt.SigningMethodHS256, secret, wrongAudience), http.StatusUnauthorized, `{"error":"invalid bearer token"}`},
+		{"", http.StatusUnauthorized, `{"error":"missing bearer token"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"whoami"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", test.authorization)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.TrimSpace(recorder.Body.String()), test.body)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	type user struct {
+		Permissions []string
+	}
+
+	authorized := make(chan cadet.CommandInfo, 1)
+
+	config := &cadet.Config{
+		Authorize: func(principal cadet.Principal, command cadet.CommandInfo) error {
+			authorized <- command
+
+			u, _ := principal.(*user)
+			for _, permission := range command.Permissions {
+				if u == nil || !slices.Contains(u.Permissions, permission) {
+					return fmt.Errorf("missing permission %s", permission)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	users := map[string]*user{
+		"admin":  {Permissions: []string{"users:read", "users:delete"}},
+		"viewer": {Permissions: []string{"users:read"}},
+	}
+
+	server := cadet.NewServer(config, "")
+	server.Use(cadet.BearerAuth(func(token string) (cadet.Principal, error) {
+		return users[token], nil
+	}))
+
+	server.Command("list-users", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.RequirePermission("users:read"))
+
+	server.Command("delete-user", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.RequirePermission("users:read", "users:delete"))
+
+	for _, test := range []struct {
+		token   string
+		command string
+		status  int
+		body    string
+	}{
+		{"admin", "delete-user", http.StatusOK, ""},
+		{"viewer", "list-users", http.StatusOK, ""},
+		{"viewer", "delete-user", http.StatusForbidden, `{"error":"missing permission users:delete"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+test.command+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.TrimSpace(recorder.Body.String()), test.body)
+		assertEqual(t, (<-authorized).Name, test.command)
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("delete-user", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.RequirePermission("users:delete"))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"delete-user"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusForbidden)
+}
+
+type scopedUser []string
+
+func (u scopedUser) Scopes() []string {
+	return u
+}
+
+func TestRequireScope(t *testing.T) {
+	errs := make(chan error, 1)
+
+	config := &cadet.Config{
+		OnError: func(r *cadet.Request, err error) cadet.Response {
+			errs <- err
+			return nil
+		},
+	}
+
+	principals := map[string]cadet.Principal{
+		"string":  jwt.MapClaims{"scope": "orders:read orders:write"},
+		"array":   jwt.MapClaims{"scp": []any{"orders:read"}},
+		"custom":  scopedUser{"orders:read", "orders:write"},
+		"missing": jwt.MapClaims{"sub": "u1"},
+	}
+
+	server := cadet.NewServer(config, "")
+	server.Use(cadet.BearerAuth(func(token string) (cadet.Principal, error) {
+		return principals[token], nil
+	}))
+
+	server.Command("place-order", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}, cadet.RequireScope("orders:read", "orders:write"))
+
+	for _, test := range []struct {
+		token  string
+		status int
+		body   string
+	}{
+		{"string", http.StatusOK, ""},
+		{"custom", http.StatusOK, ""},
+		{"array", http.StatusForbidden, `{"error":"insufficient scope","scope":"orders:write"}`},
+		{"missing", http.StatusForbidden, `{"error":"insufficient scope","scope":"orders:read"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"place-order"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.TrimSpace(recorder.Body.String()), test.body)
+
+		if test.status == http.StatusForbidden {
+			var scopeErr *cadet.ScopeError
+
+			err := <-errs
+			assertEqual(t, errors.Is(err, cadet.ErrForbidden), true)
+			assertEqual(t, errors.As(err, &scopeErr), true)
+			assertEqual(t, recorder.Header().Get("WWW-Authenticate"), fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scopeErr.Scope))
+		}
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	request := func(middleware cadet.Middleware, remote string, forwarded string) int {
+		server := cadet.NewServer(&cadet.Config{}, "")
+		server.Use(middleware)
+
+		server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Status(http.StatusOK)
+		})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"cmd"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remote
+
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	office := cadet.IPFilter([]string{"10.0.0.0/8", "203.0.113.7"}, []string{"10.66.0.0/16"})
+	proxied := cadet.IPFilter([]string{"198.51.100.0/24"}, nil, "10.0.0.0/8")
+	blocklist := cadet.IPFilter(nil, []string{"192.0.2.0/24", "2001:db8::/32"})
+
+	for _, test := range []struct {
+		middleware cadet.Middleware
+		remote     string
+		forwarded  string
+		status     int
+	}{
+		{office, "10.1.2.3:5000", "", http.StatusOK},
+		{office, "203.0.113.7:5000", "", http.StatusOK},
+		{office, "203.0.113.8:5000", "", http.StatusForbidden},
+		{office, "10.66.1.1:5000", "", http.StatusForbidden},
+		{office, "192.0.2.1:5000", "10.1.2.3", http.StatusForbidden},
+		{proxied, "10.0.0.1:5000", "198.51.100.20", http.StatusOK},
+		{proxied, "10.0.0.1:5000", "198.51.100.20, 10.0.0.2", http.StatusOK},
+		{proxied, "10.0.0.1:5000", "198.51.100.20, 192.0.2.1", http.StatusForbidden},
+		{proxied, "192.0.2.1:5000", "198.51.100.20", http.StatusForbidden},
+		{blocklist, "192.0.2.50:5000", "", http.StatusForbidden},
+		{blocklist, "[2001:db8::1]:5000", "", http.StatusForbidden},
+		{blocklist, "198.51.100.1:5000", "", http.StatusOK},
+	} {
+		assertEqual(t, request(test.middleware, test.remote, test.forwarded), test.status)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.CSRF(cadet.CSRFOptions{}))
+
+	server.Command("transfer", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	request := func(cookies []*http.Cookie, header http.Header) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"transfer"}`))
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	resp := request(nil, nil)
+	assertEqual(t, resp.Code, http.StatusOK)
+
+	issued := resp.Result().Cookies()
+	assertEqual(t, len(issued), 1)
+	assertEqual(t, issued[0].Name, "csrf_token")
+
+	token := issued[0].Value
+	session := &http.Cookie{Name: "session", Value: "s1"}
+	csrf := &http.Cookie{Name: "csrf_token", Value: token}
+
+	for _, test := range []struct {
+		cookies []*http.Cookie
+		header  http.Header
+		status  int
+	}{
+		{[]*http.Cookie{session, csrf}, http.Header{"X-Csrf-Token": {token}}, http.StatusOK},
+		{[]*http.Cookie{session, csrf}, http.Header{"X-Csrf-Token": {"forged"}}, http.StatusForbidden},
+		{[]*http.Cookie{session, csrf}, nil, http.StatusForbidden},
+		{[]*http.Cookie{session}, http.Header{"X-Csrf-Token": {token}}, http.StatusForbidden},
+		{[]*http.Cookie{session}, http.Header{"Authorization": {"Bearer abc"}}, http.StatusOK},
+	} {
+		resp := request(test.cookies, test.header)
+		assertEqual(t, resp.Code, test.status)
+	}
+
+	resp = request([]*http.Cookie{session, csrf}, http.Header{"X-Csrf-Token": {token}})
+	assertEqual(t, len(resp.Result().Cookies()), 0)
+}
+
+func TestCSRFAllowGET(t *testing.T) {
+	request := func(config *cadet.Config, header http.Header) int {
+		server := cadet.NewServer(config, "")
+		server.Use(cadet.CSRF(cadet.CSRFOptions{}))
+
+		server.Command("deleteAccount", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text("deleted")
+		})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?name=deleteAccount", nil)
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "token"})
+
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assertEqual(t, request(&cadet.Config{AllowGET: true}, nil), http.StatusForbidden)
+	assertEqual(t, request(&cadet.Config{AllowGET: true}, http.Header{"X-Csrf-Token": {"token"}}), http.StatusOK)
+	assertEqual(t, request(&cadet.Config{Methods: []string{"GET"}}, nil), http.StatusForbidden)
+	assertEqual(t, request(&cadet.Config{}, nil), http.StatusUnsupportedMediaType)
+}
+
+func TestSecureHeaders(t *testing.T) {
+	request := func(middleware cadet.Middleware) http.Header {
+		server := cadet.NewServer(&cadet.Config{}, "")
+		server.Use(middleware)
+
+		server.Command("cmd", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Status(http.StatusOK)
+		})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"cmd"}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(recorder, req)
+
+		return recorder.Header()
+	}
+
+	header := request(cadet.SecureHeaders())
+	assertEqual(t, header.Get("Strict-Transport-Security"), "max-age=31536000; includeSubDomains")
+	assertEqual(t, header.Get("X-Content-Type-Options"), "nosniff")
+	assertEqual(t, header.Get("X-Frame-Options"), "DENY")
+	assertEqual(t, header.Get("Content-Security-Policy"), "default-src 'none'; frame-ancestors 'none'")
+	assertEqual(t, header.Get("Referrer-Policy"), "no-referrer")
+
+	header = request(cadet.SecureHeaders(cadet.SecureHeadersOptions{
+		DisableHSTS:           true,
+		FrameOptions:          "SAMEORIGIN",
+		ContentSecurityPolicy: "default-src 'self'",
+	}))
+
+	assertEqual(t, header.Get("Strict-Transport-Security"), "")
+	assertEqual(t, header.Get("X-Frame-Options"), "SAMEORIGIN")
+	assertEqual(t, header.Get("Content-Security-Policy"), "default-src 'self'")
+}
+
+func TestRecordReplay(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	path := t.TempDir() + "/recordings.jsonl"
+
+	store, err := cadet.NewFileRecorder(path)
+	assertNoError(t, err)
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "", cadet.Record(cadet.RecordOptions{
+		Store:    store,
+		Redact:   []string{"password"},
+		Commands: []string{"signup"},
+	}))
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		input := &signup{}
+		if err := r.ReadCommand(input); err != nil {
+			return cadet.Status(http.StatusBadRequest)
+		}
+
+		if input.Email == "" {
+			return cadet.Error(http.StatusUnprocessableEntity, "email required")
+		}
+
+		return cadet.JSON(input.Email)
+	}
+
+	server.Command("signup", handler)
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	for _, body := range []string{
+		`{"name":"signup","data":{"email":"ada@example.com","password":"hunter2"}}`,
+		`{"name":"ping"}`,
+		`{"name":"signup","data":{"email":"","password":"hunter2"}}`,
+	} {
+		resp, err := req(http.MethodPost, "/", body)
+		assertNoError(t, err)
+		resp.Body.Close()
+	}
+
+	assertNoError(t, store.Close())
+
+	file, err := os.Open(path)
+	assertNoError(t, err)
+	defer file.Close()
+
+	recordings, err := cadet.ReadRecordings(file)
+	assertNoError(t, err)
+	assertEqual(t, len(recordings), 2)
+
+	assertEqual(t, recordings[0].Name, "signup")
+	assertEqual(t, string(recordings[0].Data), `{"email":"ada@example.com","password":"[REDACTED]"}`)
+	assertEqual(t, recordings[0].Status, http.StatusOK)
+	assertEqual(t, recordings[1].Status, http.StatusUnprocessableEntity)
+
+	local := cadet.NewServer(&cadet.Config{}, "")
+	local.Command("signup", handler)
+
+	for _, recording := range recordings {
+		result, err := local.Replay(context.Background(), recording)
+		assertNoError(t, err)
+		assertEqual(t, result.Status, recording.Status)
+	}
+}
+
+func TestChaos(t *testing.T) {
+	request := func(options cadet.ChaosOptions, command string) (*http.Response, time.Duration, error) {
+		_, req := createJSONRequest(t, &cadet.Config{}, "", cadet.Chaos(options))
+
+		start := time.Now()
+		resp, err := req(http.MethodPost, "/", `{"name":"`+command+`"}`)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return resp, time.Since(start), err
+	}
+
+	resp, _, err := request(cadet.ChaosOptions{ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable}, "cmd")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusServiceUnavailable)
+
+	resp, _, err = request(cadet.ChaosOptions{ErrorRate: 1, Commands: []string{"other"}}, "cmd")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+
+	resp, elapsed, err := request(cadet.ChaosOptions{Latency: 50 * time.Millisecond, LatencyRate: 1}, "cmd")
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+	assertEqual(t, elapsed >= 50*time.Millisecond, true)
+
+	_, _, err = request(cadet.ChaosOptions{DropRate: 1}, "cmd")
+	assertError(t, err)
+}
+
+func TestCommandMeta(t *testing.T) {
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{EnableIntrospection: true}, "")
+	server.Command("get-user", handler, cadet.WithMeta(cadet.Meta{
+		Description: "Fetches a user by ID.",
+		Tags:        []string{"users"},
+	}))
+
+	server.Command("find-user", handler, cadet.WithMeta(cadet.Meta{
+		Description: "Use get-user instead.",
+		Deprecated:  true,
+	}))
+
+	resp, err := req(http.MethodPost, "/", `{"name":"__introspect"}`)
+	assertNoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"commands":[{"name":"find-user","description":"Use get-user instead.","deprecated":true},{"name":"get-user","description":"Fetches a user by ID.","tags":["users"]}]}`)
+
+	schemas := server.OpenAPI()["components"].(map[string]any)["schemas"].(map[string]any)
+	getUser := schemas["GetUserCommand"].(map[string]any)
+	findUser := schemas["FindUserCommand"].(map[string]any)
+
+	assertEqual(t, getUser["description"], "Fetches a user by ID.")
+	assertEqual(t, strings.Join(getUser["x-tags"].([]string), ","), "users")
+	assertEqual(t, findUser["deprecated"], true)
+
+	output := &bytes.Buffer{}
+	assertNoError(t, server.ExportTypeScript(output))
+
+	for _, snippet := range []string{
+		"    /** Fetches a user by ID. */\n    getUser:",
+		"    /** @deprecated Use get-user instead. */\n    findUser:",
+	} {
+		if !strings.Contains(output.String(), snippet) {
+			t.Fatalf("expected output to contain %q, got:\n%s", snippet, output.String())
+		}
+	}
+}
+
+func TestListCommands(t *testing.T) {
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	server := cadet.NewServer(&cadet.Config{EnableIntrospection: true}, "")
+	server.Command("ping", handler)
+	server.Command("delete-user", handler, cadet.RequirePermission("users:delete"), cadet.WithMeta(cadet.Meta{Tags: []string{"users"}}))
+	server.Group("admin").Command("reset", handler, cadet.RequireScope("admin"))
+
+	commands := server.ListCommands()
+	assertEqual(t, len(commands), 3)
+
+	assertEqual(t, commands[0].Name, "admin.reset")
+	assertEqual(t, strings.Join(commands[0].Scopes, ","), "admin")
+	assertEqual(t, commands[1].Name, "delete-user")
+	assertEqual(t, strings.Join(commands[1].Permissions, ","), "users:delete")
+	assertEqual(t, strings.Join(commands[1].Meta.Tags, ","), "users")
+	assertEqual(t, commands[2].Name, "ping")
+
+	assertEqual(t, server.HasCommand("ping"), true)
+	assertEqual(t, server.HasCommand("admin.reset"), true)
+	assertEqual(t, server.HasCommand("reset"), false)
+
+	server.RemoveCommand("ping")
+	assertEqual(t, server.HasCommand("ping"), false)
+	assertEqual(t, len(server.ListCommands()), 2)
+}
+
+func TestDocs(t *testing.T) {
+	type Echo struct {
+		Text string `json:"text"`
+	}
+
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	}
+
+	for _, test := range []struct {
+		path     string
+		docs     string
+		endpoint string
+	}{
+		{"/", "/__docs", `"./"`},
+		{"/rpc", "/rpc/__docs", `"../rpc"`},
+	} {
+		server := cadet.NewServer(&cadet.Config{Path: test.path, EnableDocs: true}, "")
+		server.Command("echo", handler, cadet.WithTypes(Echo{}, Echo{}), cadet.WithMeta(cadet.Meta{
+			Description: "Echoes <text> back.",
+			Tags:        []string{"demo"},
+		}))
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.docs, nil))
+
+		assertEqual(t, recorder.Code, http.StatusOK)
+		assertEqual(t, recorder.Header().Get("Content-Type"), "text/html; charset=utf-8")
+
+		for _, snippet := range []string{
+			"<h2>echo</h2>",
+			"Echoes &lt;text&gt; back.",
+			`<span class="tag">demo</span>`,
+			`data-command="echo"`,
+			"&#34;$ref&#34;: &#34;#/$defs/Echo&#34;",
+			"const endpoint = " + test.endpoint,
+		} {
+			if !strings.Contains(recorder.Body.String(), snippet) {
+				t.Fatalf("expected docs to contain %q, got:\n%s", snippet, recorder.Body.String())
+			}
+		}
+
+		recorder = httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, test.docs, nil))
+		assertEqual(t, recorder.Code, http.StatusMethodNotAllowed)
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__docs", nil))
+	assertEqual(t, recorder.Code, http.StatusNotFound)
+}
+
+func TestDocsMiddleware(t *testing.T) {
+	verify := func(token string) (cadet.Principal, error) {
+		if token != "secret" {
+			return nil, errors.New("unknown token")
+		}
+
+		return "admin", nil
+	}
+
+	server := cadet.NewServer(&cadet.Config{EnableDocs: true}, "")
+	server.Use(cadet.BearerAuth(verify), cadet.IPFilter([]string{"192.0.2.0/24"}, nil))
+
+	server.Command("secret", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	for _, test := range []struct {
+		remote string
+		token  string
+		status int
+	}{
+		{"192.0.2.1:1234", "", http.StatusUnauthorized},
+		{"192.0.2.1:1234", "wrong", http.StatusUnauthorized},
+		{"198.51.100.1:1234", "secret", http.StatusForbidden},
+		{"192.0.2.1:1234", "secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/__docs", nil)
+		req.RemoteAddr = test.remote
+
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.Contains(recorder.Body.String(), "<h2>secret</h2>"), test.status == http.StatusOK)
+	}
+}
+func TestDeprecationHeaders(t *testing.T) {
+	called := make(chan string, 1)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	config := &cadet.Config{
+		Envelope: true,
+		OnDeprecated: func(r *cadet.Request) {
+			called <- r.GetCommandName()
+		},
+	}
+
+	server, req := createJSONRequest(t, config, "")
+	handler := func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON("ok")
+	}
+
+	server.Command("current", handler)
+	server.Command("legacy", handler, cadet.WithMeta(cadet.Meta{Deprecated: true}))
+	server.Command("retiring", handler, cadet.WithMeta(cadet.Meta{Deprecated: true, DeprecatedSince: since, Sunset: sunset}))
+
+	for _, test := range []struct {
+		name        string
+		deprecation string
+		sunset      string
+		body        string
+	}{
+		{"current", "", "", `{"ok":true,"data":"ok"}`},
+		{"legacy", "true", "", `{"ok":true,"data":"ok","warning":"command is deprecated"}`},
+		{"retiring", "@1767225600", "Thu, 31 Dec 2026 00:00:00 GMT", `{"ok":true,"data":"ok","warning":"command is deprecated"}`},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.name+`"}`)
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, resp.Header.Get("Deprecation"), test.deprecation)
+		assertEqual(t, resp.Header.Get("Sunset"), test.sunset)
+		assertEqual(t, strings.TrimSpace(string(data)), test.body)
+
+		if test.deprecation != "" {
+			assertEqual(t, <-called, test.name)
+		}
+	}
+
+	assertEqual(t, len(called), 0)
+}
+
+func TestDefaultCommand(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	server.Command("get-user", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("user")
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"get-usr"}`)
+	assertNoError(t, err)
+	resp.Body.Close()
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+
+	server.Default(func(r *cadet.Request, ctx string) cadet.Response {
+		var data map[string]string
+		r.ReadCommand(&data)
+
+		return cadet.Error(http.StatusNotFound, "unknown command "+r.GetCommandName()+" for "+data["id"])
+	})
+
+	resp, err = req(http.MethodPost, "/", `{"name":"get-usr","data":{"id":"42"}}`)
+	assertNoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusNotFound)
+	assertEqual(t, strings.TrimSpace(string(data)), `{"error":"unknown command get-usr for 42"}`)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"get-user"}`)
+	assertNoError(t, err)
+	resp.Body.Close()
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	result, err := server.Invoke(context.Background(), "anything", nil)
+	assertNoError(t, err)
+	assertEqual(t, result.Status, http.StatusNotFound)
+
+	server.Default(nil)
+
+	_, err = server.Invoke(context.Background(), "anything", nil)
+	assertEqual(t, errors.Is(err, cadet.ErrCommandNotFound), true)
+}
+
+type greetInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type greetOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+type greeter struct {
+	prefix string
+}
+
+func (g *greeter) SayHello(r *cadet.Request, input greetInput) (*greetOutput, error) {
+	return &greetOutput{g.prefix + input.Name}, nil
+}
+
+func (g *greeter) SayGoodbye(r *cadet.Request, input *greetInput) (greetOutput, error) {
+	if input.Name == "Bob" {
+		return greetOutput{}, errors.New("no goodbyes for Bob")
+	}
+
+	return greetOutput{"Bye, " + input.Name}, nil
+}
+
+func (g *greeter) Helper(name string) string {
+	return name
+}
+
+func TestRegister(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	assertNoError(t, server.Register(&greeter{"Hello, "}, cadet.WithTimeout(time.Second)))
+
+	assertEqual(t, server.HasCommand("say-hello"), true)
+	assertEqual(t, server.HasCommand("say-goodbye"), true)
+	assertEqual(t, server.HasCommand("helper"), false)
+
+	for _, test := range []struct {
+		body   string
+		status int
+		output string
+	}{
+		{`{"name":"say-hello","data":{"name":"Ada"}}`, http.StatusOK, `{"greeting":"Hello, Ada"}`},
+		{`{"name":"say-goodbye","data":{"name":"Ada"}}`, http.StatusOK, `{"greeting":"Bye, Ada"}`},
+		{`{"name":"say-goodbye","data":{"name":"Bob"}}`, http.StatusInternalServerError, ``},
+		{`{"name":"say-hello","data":{}}`, http.StatusBadRequest, `{"error":"validation failed","fields":{"name":"is required"}}`},
+		{`{"name":"say-hello","data":"Ada"}`, http.StatusBadRequest, `{"error":"json: cannot unmarshal string into Go value of type cadet_test.greetInput"}`},
+	} {
+		resp, err := req(http.MethodPost, "/", test.body)
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+		assertEqual(t, strings.TrimSpace(string(data)), test.output)
+	}
+
+	schemas := server.OpenAPI()["components"].(map[string]any)["schemas"].(map[string]any)
+	if _, ok := schemas["greetInput"]; !ok {
+		t.Fatalf("expected greetInput schema, got %v", schemas)
+	}
+
+	assertError(t, server.Register(struct{}{}))
+}
+
+func TestRoute(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	server.Route("ping").Timeout(time.Second).Handle(func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Group("billing").Route("charge").
+		Require("billing:write").
+		RequireScope("billing").
+		Meta(cadet.Meta{Description: "Charge a card"}).
+		Handle(func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Status(http.StatusOK)
+		})
+
+	commands := server.ListCommands()
+	assertEqual(t, len(commands), 2)
+
+	assertEqual(t, commands[0].Name, "billing.charge")
+	assertEqual(t, strings.Join(commands[0].Permissions, ","), "billing:write")
+	assertEqual(t, strings.Join(commands[0].Scopes, ","), "billing")
+	assertEqual(t, commands[0].Meta.Description, "Charge a card")
+	assertEqual(t, commands[1].Name, "ping")
+
+	resp, err := req(http.MethodPost, "/", `{"name":"ping"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = req(http.MethodPost, "/", `{"name":"billing.charge"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusForbidden)
+}
+
+func TestCommandMap(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	server.CommandMap(map[string]func(*cadet.Request, string) cadet.Response{
+		"ping": func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text("pong")
+		},
+		"pong": func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text("ping")
+		},
+	}, cadet.WithMeta(cadet.Meta{Tags: []string{"health"}}))
+
+	server.Group("admin").CommandMap(map[string]func(*cadet.Request, string) cadet.Response{
+		"reset": func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Status(http.StatusAccepted)
+		},
+	})
+
+	commands := server.ListCommands()
+	assertEqual(t, len(commands), 3)
+	assertEqual(t, commands[0].Name, "admin.reset")
+	assertEqual(t, strings.Join(commands[1].Meta.Tags, ","), "health")
+	assertEqual(t, strings.Join(commands[2].Meta.Tags, ","), "health")
+
+	resp, err := req(http.MethodPost, "/", `{"name":"ping"}`)
+	assertNoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, string(data), "pong")
+
+	resp, err = req(http.MethodPost, "/", `{"name":"admin.reset"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusAccepted)
+}
+
+func TestContextFactory(t *testing.T) {
+	type tenant struct {
+		Name string
+	}
+
+	server := cadet.NewServerWithFactory(&cadet.Config{}, func(r *http.Request) (*tenant, error) {
+		name := r.Header.Get("X-Tenant")
+		if name == "" {
+			return nil, errors.New("missing tenant")
+		}
+
+		return &tenant{Name: name}, nil
+	})
+
+	server.Command("whoami", func(r *cadet.Request, ctx *tenant) cadet.Response {
+		return cadet.Text(ctx.Name)
+	})
+
+	for _, test := range []struct {
+		tenant string
+		status int
+		body   string
+	}{
+		{"acme", http.StatusOK, "acme"},
+		{"globex", http.StatusOK, "globex"},
+		{"", http.StatusInternalServerError, ""},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"whoami"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", test.tenant)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, recorder.Body.String(), test.body)
+	}
+}
+
+func TestValues(t *testing.T) {
+	type account struct {
+		ID string
+	}
+
+	withAccount := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r = cadet.SetValue(r, "account", &account{ID: r.Header.Get("X-Account")})
+			r = cadet.SetValue(r, "plan", "pro")
+			h(w, r)
+		}
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(withAccount)
+
+	server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+		account, ok := cadet.Value[*account](r, "account")
+		if !ok {
+			return cadet.Status(http.StatusUnauthorized)
+		}
+
+		if _, ok := cadet.Value[int](r, "plan"); ok {
+			return cadet.Status(http.StatusInternalServerError)
+		}
+
+		if _, ok := cadet.Value[string](r, "missing"); ok {
+			return cadet.Status(http.StatusInternalServerError)
+		}
+
+		r.Set("seen", true)
+		seen, _ := cadet.Value[bool](r, "seen")
+		plan, _ := cadet.Value[string](r, "plan")
+
+		return cadet.Text(fmt.Sprintf("%s %s %t", account.ID, plan, seen))
+	})
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"whoami"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Account", "acc_1")
+	server.Handler().ServeHTTP(recorder, req)
+
+	assertEqual(t, recorder.Code, http.StatusOK)
+	assertEqual(t, recorder.Body.String(), "acc_1 pro true")
+}
+
+func TestTenants(t *testing.T) {
+	config := &cadet.Config{
+		TenantResolver: func(r *http.Request) (string, error) {
+			if r.Header.Get("X-Tenant") == "invalid" {
+				return "", errors.New("malformed tenant header")
+			}
+
+			return r.Header.Get("X-Tenant"), nil
+		},
+	}
+
+	server := cadet.NewServer(config, "shared")
+	server.Tenant("acme", "acme-db")
+	server.Tenant("globex", "globex-db")
+
+	server.Command("whoami", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.Tenant() + ":" + ctx)
+	})
+
+	for _, test := range []struct {
+		tenant string
+		status int
+		body   string
+	}{
+		{"acme", http.StatusOK, "acme:acme-db"},
+		{"globex", http.StatusOK, "globex:globex-db"},
+		{"", http.StatusOK, ":shared"},
+		{"initech", http.StatusBadRequest, `{"error":"invalid tenant: \"initech\" is not registered"}`},
+		{"invalid", http.StatusBadRequest, `{"error":"invalid tenant: malformed tenant header"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"whoami"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", test.tenant)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, strings.TrimSpace(recorder.Body.String()), test.body)
+	}
+
+	assertEqual(t, server.RemoveTenant("globex"), true)
+	assertEqual(t, server.RemoveTenant("globex"), false)
+}
+
+func TestTenantCache(t *testing.T) {
+	config := &cadet.Config{
+		TenantResolver: func(r *http.Request) (string, error) {
+			return r.Header.Get("X-Tenant"), nil
+		},
+	}
+
+	server := cadet.NewServer(config, "shared")
+	server.Tenant("a", "A-secret")
+	server.Tenant("b", "B-secret")
+
+	calls := 0
+	server.Command("secret", func(r *cadet.Request, ctx string) cadet.Response {
+		calls++
+		return cadet.Text(ctx)
+	}, cadet.WithCache(time.Minute))
+
+	for _, test := range []struct {
+		tenant string
+		body   string
+		calls  int
+	}{
+		{"a", "A-secret", 1},
+		{"b", "B-secret", 2},
+		{"a", "A-secret", 2},
+		{"", "shared", 3},
+		{"b", "B-secret", 3},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", test.tenant)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Body.String(), test.body)
+		assertEqual(t, calls, test.calls)
+	}
+}
+
+func TestCacheVariants(t *testing.T) {
+	verify := func(token string) (cadet.Principal, error) {
+		return token, nil
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.BearerAuth(verify))
+
+	calls := map[string]int{}
+	profile := func(r *cadet.Request, ctx string) cadet.Response {
+		calls[r.GetCommandName()]++
+		return cadet.Text(fmt.Sprint(r.Principal()))
+	}
+
+	server.Command("profile", profile, cadet.WithCache(time.Minute))
+	server.Command("public", profile, cadet.WithCache(time.Minute), cadet.WithCacheKey(func(r *cadet.Request) string {
+		return ""
+	}))
+
+	server.Command("login", func(r *cadet.Request, ctx string) cadet.Response {
+		calls["login"]++
+
+		return func(w http.ResponseWriter) {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprint(r.Principal())})
+			w.WriteHeader(http.StatusOK)
+		}
+	}, cadet.WithCache(time.Minute), cadet.WithCacheKey(func(r *cadet.Request) string {
+		return ""
+	}))
+
+	send := func(name string, token string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+name+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		server.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assertEqual(t, send("profile", "alice").Body.String(), "alice")
+	assertEqual(t, send("profile", "bob").Body.String(), "bob")
+	assertEqual(t, send("profile", "alice").Body.String(), "alice")
+	assertEqual(t, calls["profile"], 2)
+
+	assertEqual(t, send("public", "alice").Body.String(), "alice")
+	assertEqual(t, send("public", "bob").Body.String(), "alice")
+	assertEqual(t, calls["public"], 1)
+
+	assertEqual(t, send("login", "alice").Header().Get("Set-Cookie"), "session=alice")
+	assertEqual(t, send("login", "bob").Header().Get("Set-Cookie"), "session=bob")
+	assertEqual(t, calls["login"], 2)
+}
+
+func TestTenantFactory(t *testing.T) {
+	config := &cadet.Config{
+		TenantResolver: func(r *http.Request) (string, error) {
+			return r.Header.Get("X-Tenant"), nil
+		},
+	}
+
+	server := cadet.NewServerWithFactory(config, func(r *http.Request) (string, error) {
+		name, db, ok := cadet.TenantContext[string](r)
+		if !ok {
+			return "default-scope", nil
+		}
+
+		return name + "-scope:" + db, nil
+	})
+
+	server.Tenant("acme", "acme-db")
+
+	server.Command("scope", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(ctx)
+	})
+
+	for _, test := range []struct {
+		tenant string
+		body   string
+	}{
+		{"acme", "acme-scope:acme-db"},
+		{"", "default-scope"},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"scope"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", test.tenant)
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Body.String(), test.body)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{TrustedProxies: []string{"10.0.0.0/8"}}, "")
+	server.Use(cadet.RateLimit(cadet.RateLimitOptions{Limit: cadet.Limit{Rate: 0.001, Burst: 1}}))
+
+	server.Command("ip", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.ClientIP())
+	})
+
+	for _, test := range []struct {
+		remote    string
+		forwarded string
+		realIP    string
+		status    int
+		ip        string
+	}{
+		{"203.0.113.1:1234", "198.51.100.1", "", http.StatusOK, "203.0.113.1"},
+		{"10.0.0.1:1234", "198.51.100.2", "", http.StatusOK, "198.51.100.2"},
+		{"10.0.0.1:1234", "6.6.6.6, 198.51.100.3, 10.0.0.2", "", http.StatusOK, "198.51.100.3"},
+		{"10.0.0.1:1234", "", "198.51.100.4", http.StatusOK, "198.51.100.4"},
+		{"10.0.0.1:1234", "198.51.100.2", "", http.StatusTooManyRequests, ""},
+		{"203.0.113.2:1234", "", "198.51.100.5", http.StatusOK, "203.0.113.2"},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"ip"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = test.remote
+
+		if test.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if test.realIP != "" {
+			req.Header.Set("X-Real-IP", test.realIP)
+		}
+
+		server.Handler().ServeHTTP(recorder, req)
+
+		assertEqual(t, recorder.Code, test.status)
+		assertEqual(t, recorder.Body.String(), test.ip)
+	}
+}
+
+func TestBaseServer(t *testing.T) {
+	base := &http.Server{
+		Addr:           ":9999",
+		MaxHeaderBytes: 4096,
+		ReadTimeout:    time.Minute,
+	}
+
+	server := cadet.NewServer(&cadet.Config{Bind: "127.0.0.1:0", BaseServer: base}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	assertEqual(t, server.HTTPServer(), base)
+	assertEqual(t, base.Addr, "127.0.0.1:0")
+	assertEqual(t, base.MaxHeaderBytes, 4096)
+	assertEqual(t, base.ReadTimeout, time.Minute)
+	assertEqual(t, base.Handler, server.Handler())
+
+	defaults := cadet.NewServer(&cadet.Config{}, "")
+	assertEqual(t, defaults.HTTPServer().ReadTimeout, 5*time.Second)
+	assertEqual(t, defaults.HTTPServer().WriteTimeout, 10*time.Second)
+}
+
+func TestConnContext(t *testing.T) {
+	type baseKey struct{}
+	type connKey struct{}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	extra, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err)
+
+	config := &cadet.Config{
+		BaseContext: func(l net.Listener) context.Context {
+			return context.WithValue(context.Background(), baseKey{}, l.Addr().String())
+		},
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, conn.RemoteAddr().String())
+		},
+	}
+
+	server := cadet.NewServer(config, "")
+	server.Command("conn", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON(map[string]any{
+			"listener": r.Context().Value(baseKey{}),
+			"conn":     r.Context().Value(connKey{}) == r.RawRequest.RemoteAddr,
+		})
+	})
+
+	go server.Serve(listener)
+	server.AddListener(extra)
+	defer server.Stop(context.Background())
+
+	for _, l := range []net.Listener{listener, extra} {
+		resp, err := http.Post("http://"+l.Addr().String(), "application/json", strings.NewReader(`{"name":"conn"}`))
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, strings.TrimSpace(string(data)), `{"conn":true,"listener":"`+l.Addr().String()+`"}`)
+	}
+}
+
+func TestContentTypeResolution(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	for _, test := range []struct {
+		contentType string
+		status      int
+	}{
+		{"application/json", http.StatusOK},
+		{"Application/JSON; charset=utf-8", http.StatusOK},
+		{"  application/json ;charset=utf-8", http.StatusOK},
+		{"application/json garbage", http.StatusUnsupportedMediaType},
+		{"application/", http.StatusUnsupportedMediaType},
+		{"application", http.StatusUnsupportedMediaType},
+		{"text/plain", http.StatusUnsupportedMediaType},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"ping"}`, test.contentType)
+		assertNoError(t, err)
+		assertEqual(t, resp.StatusCode, test.status)
+	}
+}
+
+func benchmarkCommand(b *testing.B, server http.Handler, contentType string, body []byte) {
+	b.ReportAllocs()
+
+	for b.Loop() {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		server.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkJSONCommand(b *testing.B) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		data := map[string]any{}
+		r.ReadCommand(&data)
+		return cadet.JSON(data)
+	})
+
+	body := `{"name":"echo","data":{"text":"` + strings.Repeat("a", 2048) + `","count":1}}`
+	benchmarkCommand(b, server.Handler(), "application/json", []byte(body))
+}
+
+func BenchmarkMsgPackCommand(b *testing.B) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		data := map[string]any{}
+		r.ReadCommand(&data)
+		return cadet.MsgPack(data)
+	})
+
+	body, err := msgpack.Marshal(map[string]any{
+		"name": "echo",
+		"data": map[string]any{"text": strings.Repeat("a", 2048), "count": 1},
+	})
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkCommand(b, server.Handler(), "application/msgpack", body)
+}
+
+func BenchmarkTemplateResponse(b *testing.B) {
+	tmpl := template.Must(template.New("page").Parse(`<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>`))
+	items := make([]string, 100)
+
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("page", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Template(tmpl, "page", items)
+	})
+
+	benchmarkCommand(b, server.Handler(), "application/json", []byte(`{"name":"page"}`))
+}
+
+func TestRequestPoolingAfterTimeout(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "")
+
+	release := make(chan struct{})
+	names := make(chan string, 1)
+
+	server.Command("slow", func(r *cadet.Request, ctx string) cadet.Response {
+		<-release
+		names <- r.GetCommandName()
+		return cadet.Status(http.StatusOK)
+	}, cadet.WithTimeout(10*time.Millisecond))
+
+	server.Command("fast", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.GetCommandName())
+	})
+
+	resp, err := req(http.MethodPost, "/", `{"name":"slow"}`)
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusGatewayTimeout)
+
+	for range 100 {
+		resp, err := req(http.MethodPost, "/", `{"name":"fast"}`)
+		assertNoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assertNoError(t, err)
+		assertEqual(t, string(data), "fast")
+	}
+
+	close(release)
+	assertEqual(t, <-names, "slow")
+}
+
+func BenchmarkEnvelopeCommand(b *testing.B) {
+	server := cadet.NewServer(&cadet.Config{Envelope: true}, "")
+	server.Command("user", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON(map[string]string{"name": "Ada"})
+	})
+
+	benchmarkCommand(b, server.Handler(), "application/json; charset=utf-8", []byte(`{"name":"user"}`))
+}
+
+func TestResponseInfo(t *testing.T) {
+	infos := make(chan cadet.ResponseDetails, 1)
+
+	metrics := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assertEqual(t, cadet.ResponseInfo(r), cadet.ResponseDetails{Status: http.StatusOK})
+			h(w, r)
+			infos <- cadet.ResponseInfo(r)
+		}
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{Envelope: true}, "", metrics)
+
+	server.Command("teapot", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Error(http.StatusTeapot, "short and stout")
+	})
+
+	server.Command("empty", func(r *cadet.Request, ctx string) cadet.Response {
+		return nil
+	})
+
+	for _, test := range []struct {
+		command string
+		status  int
+		body    string
+	}{
+		{"teapot", http.StatusTeapot, `{"ok":false,"error":"short and stout"}` + "\n"},
+		{"empty", http.StatusOK, `{"ok":true}` + "\n"},
+		{"unknown", http.StatusNotFound, `{"ok":false,"error":"Not Found"}` + "\n"},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.command+`"}`)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		info := <-infos
+		assertEqual(t, info.Status, test.status)
+		assertEqual(t, info.Size, int64(len(test.body)))
+		assertEqual(t, info.Written, true)
+	}
+
+	assertEqual(t, cadet.ResponseInfo(httptest.NewRequest(http.MethodPost, "/", nil)), cadet.ResponseDetails{})
+}
+
+func TestCommandName(t *testing.T) {
+	names := make(chan [2]string, 1)
+
+	audit := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			before := cadet.CommandName(r)
+			h(w, r)
+			names <- [2]string{before, cadet.CommandName(r)}
+		}
+	}
+
+	limit := cadet.RateLimit(cadet.RateLimitOptions{Limit: cadet.Limit{Rate: 100, Burst: 100}})
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "", audit, limit)
+
+	server.Command("greet", func(r *cadet.Request, ctx string) cadet.Response {
+		var data struct {
+			Name string `json:"name"`
+		}
+
+		if err := r.ReadCommand(&data); err != nil {
+			return cadet.Error(http.StatusBadRequest, err.Error())
+		}
+
+		return cadet.Text("hello " + data.Name)
+	})
+
+	for _, test := range []struct {
+		body   string
+		status int
+		name   string
+	}{
+		{`{"name":"greet","data":{"name":"cadet"}}`, http.StatusOK, "greet"},
+		{`{"name":"unknown"}`, http.StatusNotFound, "unknown"},
+		{`{"name":`, http.StatusUnprocessableEntity, ""},
+	} {
+		resp, err := req(http.MethodPost, "/", test.body)
+		assertNoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+		if test.status == http.StatusOK {
+			assertEqual(t, string(body), "hello cadet")
+		}
+
+		got := <-names
+		assertEqual(t, got, [2]string{test.name, test.name})
+	}
+
+	assertEqual(t, cadet.CommandName(httptest.NewRequest(http.MethodPost, "/", nil)), "")
+}
+
+func TestCommandMiddleware(t *testing.T) {
+	var calls []string
+
+	trace := func(name string) cadet.CommandMiddleware[string] {
+		return func(next cadet.CommandHandler[string]) cadet.CommandHandler[string] {
+			return func(r *cadet.Request, ctx string) cadet.Response {
+				calls = append(calls, name+":"+r.GetCommandName())
+				return next(r, ctx)
+			}
+		}
+	}
+
+	requireOwner := func(next cadet.CommandHandler[string]) cadet.CommandHandler[string] {
+		return func(r *cadet.Request, ctx string) cadet.Response {
+			var data struct {
+				Owner string `json:"owner"`
+			}
+
+			if err := r.ReadCommand(&data); err != nil || data.Owner != ctx {
+				return cadet.Error(http.StatusForbidden, "not the owner")
+			}
+
+			return next(r, ctx)
+		}
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{}, "alice")
+	server.UseCommand(trace("server"))
+
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("pong")
+	})
+
+	admin := server.Group("admin")
+	admin.UseCommand(trace("admin"), requireOwner)
+
+	users := admin.Group("users")
+	users.UseCommand(trace("users"))
+
+	users.Command("delete", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("deleted")
+	})
+
+	for _, test := range []struct {
+		body   string
+		status int
+		text   string
+		calls  []string
+	}{
+		{`{"name":"ping"}`, http.StatusOK, "pong", []string{"server:ping"}},
+		{`{"name":"admin.users.delete","data":{"owner":"alice"}}`, http.StatusOK, "deleted", []string{"server:admin.users.delete", "admin:admin.users.delete", "users:admin.users.delete"}},
+		{`{"name":"admin.users.delete","data":{"owner":"bob"}}`, http.StatusForbidden, `{"error":"not the owner"}`, []string{"server:admin.users.delete", "admin:admin.users.delete"}},
+	} {
+		calls = nil
+
+		resp, err := req(http.MethodPost, "/", test.body)
+		assertNoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+		assertEqual(t, strings.TrimSpace(string(body)), test.text)
+		assertEqual(t, strings.Join(calls, ","), strings.Join(test.calls, ","))
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	server, req := createJSONRequest(t, &cadet.Config{}, "v2")
+
+	server.Before(func(r *cadet.Request, ctx string) cadet.Response {
+		if r.GetCommandName() == "beta" && ctx != "v3" {
+			return cadet.Error(http.StatusForbidden, "feature disabled")
+		}
+
+		return nil
+	})
+
+	server.Before(func(r *cadet.Request, ctx string) cadet.Response {
+		if r.GetCommandName() == "beta" {
+			t.Error("expected earlier before hook to short-circuit")
+		}
+
+		return nil
+	})
+
+	server.After(func(r *cadet.Request, ctx string, response cadet.Response) cadet.Response {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("X-Version", ctx)
+
+			if response != nil {
+				response(w)
+			}
+		}
+	})
+
+	server.After(func(r *cadet.Request, ctx string, response cadet.Response) cadet.Response {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("X-Command", r.GetCommandName())
+			response(w)
+		}
+	})
+
+	server.Command("stable", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("stable")
+	})
+
+	server.Command("beta", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("beta")
+	})
+
+	server.Command("empty", func(r *cadet.Request, ctx string) cadet.Response {
+		return nil
+	})
+
+	for _, test := range []struct {
+		command string
+		status  int
+		body    string
+	}{
+		{"stable", http.StatusOK, "stable"},
+		{"beta", http.StatusForbidden, `{"error":"feature disabled"}`},
+		{"empty", http.StatusOK, ""},
+	} {
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.command+`"}`)
+		assertNoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+		assertEqual(t, strings.TrimSpace(string(body)), test.body)
+		assertEqual(t, resp.Header.Get("X-Version"), "v2")
+		assertEqual(t, resp.Header.Get("X-Command"), test.command)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var events []string
+
+	hooks := &cadet.Hooks{
+		OnRequest: func(r *cadet.Request) {
+			events = append(events, "request:"+r.GetCommandName())
+		},
+		OnResponse: func(r *cadet.Request, response cadet.ResponseDetails, duration time.Duration) {
+			events = append(events, fmt.Sprintf("response:%s:%d:%d", r.GetCommandName(), response.Status, response.Size))
+
+			if duration <= 0 {
+				t.Errorf("expected positive duration, got %v", duration)
+			}
+		},
+		OnPanic: func(r *cadet.Request, recovered any, stack []byte) {
+			events = append(events, fmt.Sprintf("panic:%s:%v", r.GetCommandName(), recovered))
+		},
+		OnDecodeError: func(r *http.Request, err error) {
+			events = append(events, "decode:"+strconv.FormatBool(errors.Is(err, cadet.ErrInvalidCommand)))
+		},
+	}
+
+	server, req := createJSONRequest(t, &cadet.Config{Hooks: hooks}, "")
+
+	server.Command("ok", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("ok")
+	})
+
+	server.Command("boom", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	})
+
+	for _, test := range []struct {
+		body   string
+		status int
+		events []string
+	}{
+		{`{"name":"ok"}`, http.StatusOK, []string{"request:ok", "response:ok:200:2"}},
+		{`{"name":"boom"}`, http.StatusInternalServerError, []string{"request:boom", "panic:boom:boom", "response:boom:500:0"}},
+		{`{"name":`, http.StatusUnprocessableEntity, []string{"decode:true"}},
+		{`{"name":"unknown"}`, http.StatusNotFound, nil},
+	} {
+		events = nil
+
+		resp, err := req(http.MethodPost, "/", test.body)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+		assertEqual(t, strings.Join(events, ","), strings.Join(test.events, ","))
+	}
+}
+
+type report struct {
+	err  error
+	meta cadet.ErrorMeta
+}
+
+type testReporter struct {
+	reports []report
+}
+
+func (r *testReporter) Report(ctx context.Context, err error, meta cadet.ErrorMeta) {
+	r.reports = append(r.reports, report{err, meta})
+}
+
+func TestErrorReporter(t *testing.T) {
+	reporter := &testReporter{}
+	failed := errors.New("database unavailable")
+
+	server, req := createJSONRequest(t, &cadet.Config{ErrorReporter: reporter}, "", cadet.RequestID())
+
+	server.Command("ok", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("ok")
+	})
+
+	server.Command("bad", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusBadRequest)
+	})
+
+	server.Command("fail", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Fail(failed)
+	})
+
+	server.Command("unavailable", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusServiceUnavailable)
+	})
+
+	server.Command("boom", func(r *cadet.Request, ctx string) cadet.Response {
+		panic("boom")
+	})
+
+	for _, test := range []struct {
+		command string
+		status  int
+		err     string
+	}{
+		{"ok", http.StatusOK, ""},
+		{"bad", http.StatusBadRequest, ""},
+		{"fail", http.StatusInternalServerError, "database unavailable"},
+		{"unavailable", http.StatusServiceUnavailable, "command responded with 503 Service Unavailable"},
+		{"boom", http.StatusInternalServerError, "panic: boom"},
+	} {
+		reporter.reports = nil
+
+		resp, err := req(http.MethodPost, "/", `{"name":"`+test.command+`"}`)
+		assertNoError(t, err)
+		resp.Body.Close()
+
+		assertEqual(t, resp.StatusCode, test.status)
+
+		if test.err == "" {
+			assertEqual(t, len(reporter.reports), 0)
+			continue
+		}
+
+		assertEqual(t, len(reporter.reports), 1)
+		assertEqual(t, reporter.reports[0].err.Error(), test.err)
+		assertEqual(t, reporter.reports[0].meta, cadet.ErrorMeta{
+			Command:   test.command,
+			RequestID: resp.Header.Get("X-Request-ID"),
+			Status:    test.status,
+		})
+	}
+
+	var panicErr *cadet.PanicError
+	if !errors.As(reporter.reports[0].err, &panicErr) || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected panic error with stack, got %v", reporter.reports[0].err)
+	}
+}
+
+func TestJSONDecoderReuse(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		server, req := createJSONRequest(t, &cadet.Config{StrictDecode: strict}, "")
+
+		server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+			var text string
+			if err := r.ReadCommand(&text); err != nil {
+				return cadet.Error(http.StatusBadRequest, err.Error())
+			}
+
+			return cadet.Text(text)
+		})
+
+		large := strings.Repeat("x", 100<<10)
+
+		for i, test := range []struct {
+			body   string
+			status int
+			text   string
+		}{
+			{`{"name":"echo","data":"one"}`, http.StatusOK, "one"},
+			{`{"name":"echo","data":"two"} {"name":"echo"}`, http.StatusUnprocessableEntity, ""},
+			{`{"name":"echo","data":"three"}` + "\n\n", http.StatusOK, "three"},
+			{`{"name":"echo","data":`, http.StatusUnprocessableEntity, ""},
+			{`{"name":"echo","data":"four"}}`, http.StatusUnprocessableEntity, ""},
+			{`{"name":"echo","data":"` + large + `"}`, http.StatusOK, large},
+			{``, http.StatusUnprocessableEntity, ""},
+			{`  {"name":"echo","data":"five"}`, http.StatusOK, "five"},
+			{`{"name":"echo","data":"six"}`, http.StatusOK, "six"},
+		} {
+			resp, err := req(http.MethodPost, "/", test.body)
+			assertNoError(t, err)
+
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+			resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("strict=%v request %d: expected status %d, got %d", strict, i, test.status, resp.StatusCode)
+			}
+
+			if test.status == http.StatusOK {
+				assertEqual(t, string(body), test.text)
+			}
+		}
+	}
+}
+
+func TestRequestRetention(t *testing.T) {
+	serve := func(server *cadet.Server[string], name string) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+name+`","data":"payload"}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("hooks", func(t *testing.T) {
+		var kept []*cadet.Request
+
+		server := cadet.NewServer(&cadet.Config{
+			Hooks: &cadet.Hooks{
+				OnRequest: func(r *cadet.Request) {
+					kept = append(kept, r)
+				},
+			},
+			OnError: func(r *cadet.Request, err error) cadet.Response {
+				kept = append(kept, r)
+				return nil
+			},
+		}, "")
+
+		server.Command("ok", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text("ok")
+		})
+
+		server.Command("fail", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Fail(errors.New("failed"))
+		})
+
+		serve(server, "ok")
+		serve(server, "fail")
+		serve(server, "ok")
+
+		names := []string{}
+		for _, r := range kept {
+			var data string
+			assertNoError(t, r.ReadCommand(&data))
+			assertEqual(t, data, "payload")
+
+			names = append(names, r.GetCommandName())
+		}
+
+		assertEqual(t, strings.Join(names, ","), "ok,fail,fail,ok")
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		var kept []*cadet.Request
+
+		server := cadet.NewServer(&cadet.Config{}, "")
+		server.Command("keep", func(r *cadet.Request, ctx string) cadet.Response {
+			kept = append(kept, r)
+			return cadet.Text("ok")
+		})
+
+		server.Command("other", func(r *cadet.Request, ctx string) cadet.Response {
+			return cadet.Text("ok")
+		})
+
+		for _, user := range []string{"alice", "bob"} {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"keep","data":"`+user+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User", user)
+			server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+			serve(server, "other")
+		}
+
+		assertEqual(t, len(kept), 2)
+		assertEqual(t, kept[0] != kept[1], true)
+
+		for i, user := range []string{"alice", "bob"} {
+			var data string
+			assertNoError(t, kept[i].ReadCommand(&data))
+			assertEqual(t, data, user)
+			assertEqual(t, kept[i].GetCommandName(), "keep")
+			assertEqual(t, kept[i].RawRequest.Header.Get("X-User"), user)
+		}
+	})
+}
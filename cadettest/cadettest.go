@@ -0,0 +1,108 @@
+package cadettest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/martinrue/cadet"
+	"github.com/martinrue/cadet/internal/transport"
+)
+
+type Option func(*invocation)
+
+type invocation struct {
+	ctx     context.Context
+	path    string
+	header  http.Header
+	ignored []string
+	dir     string
+	golden  string
+}
+
+type StatusError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("cadettest: unexpected status %d: %s", e.Status, e.Body)
+}
+
+type envelope struct {
+	Name string `json:"name"`
+	Data any    `json:"data,omitempty"`
+}
+
+func WithPath(path string) Option {
+	return func(i *invocation) {
+		i.path = path
+	}
+}
+
+func WithContext(ctx context.Context) Option {
+	return func(i *invocation) {
+		i.ctx = ctx
+	}
+}
+
+func WithHeader(name string, value string) Option {
+	return func(i *invocation) {
+		i.header.Add(name, value)
+	}
+}
+
+func Invoke(handler http.Handler, name string, input any, output any, options ...Option) (cadet.Result, error) {
+	result, err := newInvocation(options).serve(handler, name, input)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Status < http.StatusOK || result.Status >= http.StatusMultipleChoices {
+		return result, &StatusError{result.Status, result.Body}
+	}
+
+	if output != nil {
+		if err := result.Decode(output); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func newInvocation(options []Option) *invocation {
+	i := &invocation{
+		ctx:    context.Background(),
+		path:   "/",
+		header: http.Header{},
+		dir:    "testdata",
+	}
+
+	for _, option := range options {
+		option(i)
+	}
+
+	return i
+}
+
+func (i *invocation) serve(handler http.Handler, name string, input any) (cadet.Result, error) {
+	body, err := json.Marshal(&envelope{name, input})
+	if err != nil {
+		return cadet.Result{}, err
+	}
+
+	req, err := transport.NewRequest(i.ctx, i.path, body)
+	if err != nil {
+		return cadet.Result{}, err
+	}
+
+	for key, values := range i.header {
+		req.Header[key] = values
+	}
+
+	rec := transport.Serve(handler, req)
+
+	return cadet.Result{Status: rec.Status, Header: rec.Header(), Body: rec.Body.Bytes()}, nil
+}
@@ -0,0 +1,235 @@
+package cadettest_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinrue/cadet"
+	"github.com/martinrue/cadet/cadettest"
+	"github.com/martinrue/cadet/client"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type addInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type addOutput struct {
+	Sum int `json:"sum"`
+}
+
+func TestInvoke(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.BearerAuth(func(token string) (cadet.Principal, error) {
+		if token != "secret" {
+			return nil, errors.New("invalid")
+		}
+
+		return token, nil
+	}))
+
+	server.Command("add", func(r *cadet.Request, ctx string) cadet.Response {
+		input := &addInput{}
+		if err := r.ReadCommand(input); err != nil {
+			return cadet.Status(http.StatusBadRequest)
+		}
+
+		return cadet.JSON(&addOutput{input.A + input.B})
+	})
+
+	output := &addOutput{}
+	result, err := cadettest.Invoke(server, "add", &addInput{2, 3}, output, cadettest.WithHeader("Authorization", "Bearer secret"))
+	assertNoError(t, err)
+	assertEqual(t, result.Status, http.StatusOK)
+	assertEqual(t, output.Sum, 5)
+
+	var statusErr *cadettest.StatusError
+
+	result, err = cadettest.Invoke(server, "add", &addInput{2, 3}, output)
+	assertEqual(t, errors.As(err, &statusErr), true)
+	assertEqual(t, statusErr.Status, http.StatusUnauthorized)
+	assertEqual(t, result.Status, http.StatusUnauthorized)
+
+	_, err = cadettest.Invoke(server, "missing", nil, nil, cadettest.WithHeader("Authorization", "Bearer secret"))
+	assertEqual(t, errors.As(err, &statusErr), true)
+	assertEqual(t, statusErr.Status, http.StatusNotFound)
+}
+
+func TestInvokeWithPath(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{Path: "/api"}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.JSON("pong")
+	})
+
+	var output string
+
+	_, err := cadettest.Invoke(server, "ping", nil, &output, cadettest.WithPath("/api"))
+	assertNoError(t, err)
+	assertEqual(t, output, "pong")
+}
+
+type billing struct {
+	client client.Caller
+}
+
+func (b *billing) charge(ctx context.Context, amount int) (string, error) {
+	var receipt struct {
+		ID string `json:"id"`
+	}
+
+	err := b.client.Call(ctx, "charge", map[string]int{"amount": amount}, &receipt)
+	return receipt.ID, err
+}
+
+func TestFakeClient(t *testing.T) {
+	declined := &client.Error{Status: http.StatusPaymentRequired, Message: "card declined"}
+
+	fake := cadettest.NewFakeClient().
+		Respond("charge", map[string]string{"id": "r1"}).
+		Fail("charge", declined).
+		Respond("charge", map[string]string{"id": "r2"})
+
+	service := &billing{fake}
+
+	id, err := service.charge(context.Background(), 100)
+	assertNoError(t, err)
+	assertEqual(t, id, "r1")
+
+	_, err = service.charge(context.Background(), 200)
+	assertEqual(t, errors.Is(err, declined), true)
+
+	for range 2 {
+		id, err = service.charge(context.Background(), 300)
+		assertNoError(t, err)
+		assertEqual(t, id, "r2")
+	}
+
+	calls := fake.CallsTo("charge")
+	assertEqual(t, len(calls), 4)
+	assertEqual(t, string(calls[1].Input), `{"amount":200}`)
+
+	var clientErr *client.Error
+
+	err = fake.Call(context.Background(), "refund", nil, nil)
+	assertEqual(t, errors.As(err, &clientErr), true)
+	assertEqual(t, clientErr.Status, http.StatusNotFound)
+	assertEqual(t, len(fake.Calls()), 5)
+}
+
+type failureRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (f *failureRecorder) Errorf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *failureRecorder) Fatalf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertGolden(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadet.RequestID())
+
+	server.Command("user", func(r *cadet.Request, ctx string) cadet.Response {
+		var input struct {
+			Name string `json:"name"`
+		}
+
+		r.ReadCommand(&input)
+
+		return cadet.JSON(map[string]any{"name": input.Name, "roles": []string{"admin"}}).
+			WithStatus(http.StatusCreated).
+			WithHeader("ETag", `"v1"`)
+	})
+
+	dir := t.TempDir()
+
+	t.Run("ada", func(t *testing.T) {
+		expected := "201 Created\n" +
+			"Content-Type: application/json; charset=utf-8\n" +
+			"Etag: \"v1\"\n" +
+			"\n" +
+			"{\n  \"name\": \"Ada\",\n  \"roles\": [\n    \"admin\"\n  ]\n}\n"
+
+		assertNoError(t, os.MkdirAll(filepath.Join(dir, "TestAssertGolden", "ada"), 0o755))
+		assertNoError(t, os.WriteFile(filepath.Join(dir, "TestAssertGolden", "ada", "user.golden"), []byte(expected), 0o644))
+
+		recorder := &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Ada"}, cadettest.WithGoldenDir(dir))
+		assertEqual(t, len(recorder.failures), 0)
+
+		recorder = &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Grace"}, cadettest.WithGoldenDir(dir))
+		assertEqual(t, len(recorder.failures), 1)
+
+		recorder = &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Ada"}, cadettest.WithGoldenDir(dir), cadettest.IgnoreHeader("etag"))
+		assertEqual(t, len(recorder.failures), 1)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		recorder := &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", nil, cadettest.WithGoldenDir(dir))
+		assertEqual(t, len(recorder.failures), 1)
+		assertEqual(t, strings.Contains(recorder.failures[0], "-cadettest.update"), true)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		assertNoError(t, flag.Set("cadettest.update", "true"))
+		defer flag.Set("cadettest.update", "false")
+
+		cadettest.AssertGolden(t, server, "user", map[string]string{"name": "Ada"}, cadettest.WithGoldenDir(dir))
+
+		data, err := os.ReadFile(filepath.Join(dir, "TestAssertGolden", "update", "user.golden"))
+		assertNoError(t, err)
+		assertEqual(t, strings.HasPrefix(string(data), "201 Created\n"), true)
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		assertNoError(t, flag.Set("cadettest.update", "true"))
+		defer flag.Set("cadettest.update", "false")
+
+		cadettest.AssertGolden(t, server, "user", map[string]string{"name": "Ada"}, cadettest.WithGoldenDir(dir))
+		cadettest.AssertGolden(t, server, "ping", nil, cadettest.WithGoldenDir(dir))
+		cadettest.AssertGolden(t, server, "user", map[string]string{"name": "Grace"}, cadettest.WithGoldenDir(dir), cadettest.WithGoldenName("user-grace"))
+
+		recorder := &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Linus"}, cadettest.WithGoldenDir(dir))
+		assertEqual(t, len(recorder.failures), 1)
+		assertEqual(t, strings.Contains(recorder.failures[0], "WithGoldenName"), true)
+
+		assertNoError(t, flag.Set("cadettest.update", "false"))
+
+		recorder = &failureRecorder{TB: t}
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Ada"}, cadettest.WithGoldenDir(dir))
+		cadettest.AssertGolden(recorder, server, "ping", nil, cadettest.WithGoldenDir(dir))
+		cadettest.AssertGolden(recorder, server, "user", map[string]string{"name": "Grace"}, cadettest.WithGoldenDir(dir), cadettest.WithGoldenName("user-grace"))
+		assertEqual(t, len(recorder.failures), 0)
+	})
+}
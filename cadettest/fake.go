@@ -0,0 +1,112 @@
+package cadettest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/martinrue/cadet/client"
+)
+
+type Call struct {
+	Name  string
+	Input json.RawMessage
+}
+
+type fakeResponse struct {
+	output any
+	err    error
+}
+
+type FakeClient struct {
+	mu        sync.Mutex
+	responses map[string][]fakeResponse
+	calls     []Call
+}
+
+var _ client.Caller = (*FakeClient)(nil)
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{responses: make(map[string][]fakeResponse)}
+}
+
+func (f *FakeClient) Respond(name string, output any) *FakeClient {
+	return f.script(name, fakeResponse{output: output})
+}
+
+func (f *FakeClient) Fail(name string, err error) *FakeClient {
+	return f.script(name, fakeResponse{err: err})
+}
+
+func (f *FakeClient) script(name string, response fakeResponse) *FakeClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[name] = append(f.responses[name], response)
+	return f
+}
+
+func (f *FakeClient) Call(ctx context.Context, name string, in any, out any) error {
+	input, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+
+	f.calls = append(f.calls, Call{name, input})
+
+	queue := f.responses[name]
+	if len(queue) == 0 {
+		f.mu.Unlock()
+		return &client.Error{Status: http.StatusNotFound, Message: http.StatusText(http.StatusNotFound)}
+	}
+
+	response := queue[0]
+	if len(queue) > 1 {
+		f.responses[name] = queue[1:]
+	}
+
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if response.err != nil {
+		return response.err
+	}
+
+	if out == nil || response.output == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(response.output)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *FakeClient) CallsTo(name string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := []Call{}
+	for _, call := range f.calls {
+		if call.Name == name {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
@@ -0,0 +1,166 @@
+package cadettest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/martinrue/cadet"
+)
+
+var update = flag.Bool("cadettest.update", false, "rewrite golden files with the current responses")
+
+var volatileHeaders = []string{"Date", "X-Request-Id"}
+
+var written = struct {
+	mu    sync.Mutex
+	paths map[string]map[string]bool
+}{paths: map[string]map[string]bool{}}
+
+func WithGoldenDir(dir string) Option {
+	return func(i *invocation) {
+		i.dir = dir
+	}
+}
+
+func WithGoldenName(name string) Option {
+	return func(i *invocation) {
+		i.golden = name
+	}
+}
+
+func IgnoreHeader(name string) Option {
+	return func(i *invocation) {
+		i.ignored = append(i.ignored, http.CanonicalHeaderKey(name))
+	}
+}
+
+func AssertGolden(t testing.TB, handler http.Handler, name string, input any, options ...Option) {
+	t.Helper()
+
+	i := newInvocation(options)
+
+	result, err := i.serve(handler, name, input)
+	if err != nil {
+		t.Fatalf("cadettest: invoking %s: %v", name, err)
+		return
+	}
+
+	actual := formatResult(result, append(volatileHeaders, i.ignored...))
+	file := i.golden
+	if file == "" {
+		file = name
+	}
+
+	path := filepath.Join(i.dir, goldenName(t.Name()), goldenName(file)+".golden")
+
+	if *update {
+		if !markWritten(t, path) {
+			t.Fatalf("cadettest: %s was already written by this test, use cadettest.WithGoldenName to give each call its own file", path)
+			return
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("cadettest: %v", err)
+			return
+		}
+
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("cadettest: %v", err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cadettest: reading golden file (run with -cadettest.update to create it): %v", err)
+		return
+	}
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("cadettest: response for %s does not match %s\n--- expected\n%s\n--- actual\n%s", name, path, expected, actual)
+	}
+}
+
+func formatResult(result cadet.Result, ignored []string) []byte {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "%d %s\n", result.Status, http.StatusText(result.Status))
+
+	names := make([]string, 0, len(result.Header))
+	for name := range result.Header {
+		if !slices.Contains(ignored, name) {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		for _, value := range result.Header[name] {
+			fmt.Fprintf(buffer, "%s: %s\n", name, value)
+		}
+	}
+
+	buffer.WriteString("\n")
+
+	body := bytes.TrimSpace(result.Body)
+	if json.Valid(body) {
+		indented := &bytes.Buffer{}
+		json.Indent(indented, body, "", "  ")
+		body = indented.Bytes()
+	}
+
+	if len(body) > 0 {
+		buffer.Write(body)
+		buffer.WriteString("\n")
+	}
+
+	return buffer.Bytes()
+}
+
+func markWritten(t testing.TB, path string) bool {
+	written.mu.Lock()
+	defer written.mu.Unlock()
+
+	test := t.Name()
+
+	paths, ok := written.paths[test]
+	if !ok {
+		paths = map[string]bool{}
+		written.paths[test] = paths
+
+		t.Cleanup(func() {
+			written.mu.Lock()
+			defer written.mu.Unlock()
+
+			delete(written.paths, test)
+		})
+	}
+
+	if paths[path] {
+		return false
+	}
+
+	paths[path] = true
+	return true
+}
+
+func goldenName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+
+		return r
+	}, name)
+}
@@ -0,0 +1,74 @@
+package cadet
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+var cborDecoder, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any{})}.DecMode()
+
+type CBORCodec struct{}
+
+func (CBORCodec) Decode(r *http.Request) (*Command, error) {
+	defer r.Body.Close()
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	if _, err := buffer.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	envelope := &struct {
+		Name  string          `cbor:"name"`
+		Data  cbor.RawMessage `cbor:"data"`
+		Async bool            `cbor:"async"`
+	}{}
+
+	if err := cborDecoder.Unmarshal(buffer.Bytes(), envelope); err != nil {
+		return nil, err
+	}
+
+	command := newCommand()
+	command.Name = envelope.Name
+	command.Async = envelope.Async
+
+	if len(envelope.Data) > 0 {
+		var data any
+		if err := cborDecoder.Unmarshal(envelope.Data, &data); err != nil {
+			return nil, err
+		}
+
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+
+		command.Data = encoded
+	}
+
+	return command, nil
+}
+
+func (CBORCodec) Encode(w http.ResponseWriter, v any) error {
+	encoded, err := cbor.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/cbor")
+	_, err = w.Write(encoded)
+
+	return err
+}
+
+func CBOR(response any) Response {
+	return func(w http.ResponseWriter) {
+		if err := (CBORCodec{}).Encode(w, response); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cadet
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type ChaosOptions struct {
+	Latency     time.Duration
+	LatencyRate float64
+	ErrorRate   float64
+	ErrorStatus int
+	DropRate    float64
+	Commands    []string
+}
+
+func Chaos(options ChaosOptions) Middleware {
+	if options.ErrorStatus == 0 {
+		options.ErrorStatus = http.StatusInternalServerError
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if len(options.Commands) > 0 && !slices.Contains(options.Commands, peekCommandName(r)) {
+				h(w, r)
+				return
+			}
+
+			if chance(options.LatencyRate) {
+				timer := time.NewTimer(options.Latency)
+
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+				}
+			}
+
+			if chance(options.DropRate) {
+				panic(http.ErrAbortHandler)
+			}
+
+			if chance(options.ErrorRate) {
+				Error(options.ErrorStatus, "chaos: injected failure")(w)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+}
+
+func chance(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+type Option func(*Client)
+
+type Caller interface {
+	Call(ctx context.Context, name string, in any, out any) error
+}
+
+var _ Caller = (*Client)(nil)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cadet: %d %s", e.Status, e.Message)
+}
+
+type envelope struct {
+	Name string `json:"name"`
+	Data any    `json:"data,omitempty"`
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func New(baseURL string, options ...Option) *Client {
+	client := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+func (c *Client) Call(ctx context.Context, name string, in any, out any) error {
+	body, err := json.Marshal(&envelope{name, in})
+	if err != nil {
+		return err
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readError(resp, data)
+	}
+
+	return readResult(resp, data, out)
+}
+
+func readError(resp *http.Response, data []byte) error {
+	result := &struct {
+		Error string `json:"error"`
+	}{}
+
+	if isJSON(resp) && json.Unmarshal(data, result) == nil && result.Error != "" {
+		return &Error{resp.StatusCode, result.Error}
+	}
+
+	return &Error{resp.StatusCode, http.StatusText(resp.StatusCode)}
+}
+
+func readResult(resp *http.Response, data []byte, out any) error {
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if text, ok := out.(*string); ok && !isJSON(resp) {
+		*text = string(data)
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func isJSON(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,120 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/martinrue/cadet"
+	"github.com/martinrue/cadet/client"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createServer(t *testing.T, commands ...any) string {
+	t.Helper()
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	assertNoError(t, server.Commands(commands...))
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return httpServer.URL
+}
+
+func TestCall(t *testing.T) {
+	type echo struct {
+		Text string `json:"text"`
+	}
+
+	url := createServer(t, "echo", func(r *cadet.Request, ctx string) cadet.Response {
+		in := &echo{}
+		if err := r.ReadCommand(in); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.JSON(&echo{in.Text + in.Text})
+	})
+
+	out := &echo{}
+	err := client.New(url).Call(context.Background(), "echo", &echo{"yo"}, out)
+
+	assertNoError(t, err)
+	assertEqual(t, out.Text, "yoyo")
+}
+
+func TestCallText(t *testing.T) {
+	url := createServer(t, "text", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("hello")
+	})
+
+	out := ""
+	err := client.New(url).Call(context.Background(), "text", nil, &out)
+
+	assertNoError(t, err)
+	assertEqual(t, out, "hello")
+}
+
+func TestCallError(t *testing.T) {
+	url := createServer(t, "fail", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Error(http.StatusConflict, "already exists")
+	})
+
+	err := client.New(url).Call(context.Background(), "fail", nil, nil)
+
+	callErr := &client.Error{}
+	assertEqual(t, errors.As(err, &callErr), true)
+	assertEqual(t, callErr.Status, http.StatusConflict)
+	assertEqual(t, callErr.Message, "already exists")
+
+	err = client.New(url).Call(context.Background(), "unknown", nil, nil)
+
+	assertEqual(t, errors.As(err, &callErr), true)
+	assertEqual(t, callErr.Status, http.StatusNotFound)
+	assertEqual(t, callErr.Message, "Not Found")
+}
+
+func TestCallTimeout(t *testing.T) {
+	url := createServer(t, "slow", func(r *cadet.Request, ctx string) cadet.Response {
+		time.Sleep(200 * time.Millisecond)
+		return cadet.Status(http.StatusOK)
+	})
+
+	err := client.New(url, client.WithTimeout(20*time.Millisecond)).Call(context.Background(), "slow", nil, nil)
+
+	if err == nil {
+		t.Fatal("expected timeout error, got nil")
+	}
+}
+
+func TestTimeoutWithHTTPClient(t *testing.T) {
+	url := createServer(t, "slow", func(r *cadet.Request, ctx string) cadet.Response {
+		time.Sleep(200 * time.Millisecond)
+		return cadet.Status(http.StatusOK)
+	})
+
+	httpClient := &http.Client{}
+
+	err := client.New(url, client.WithHTTPClient(httpClient), client.WithTimeout(20*time.Millisecond)).Call(context.Background(), "slow", nil, nil)
+
+	assertEqual(t, errors.Is(err, context.DeadlineExceeded), true)
+	assertEqual(t, httpClient.Timeout, time.Duration(0))
+}
@@ -0,0 +1,40 @@
+package cadet
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+)
+
+type clientIPKey struct{}
+
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(netip.Addr); ok {
+		return ip.String()
+	}
+
+	return remoteIP(r)
+}
+
+func (c *Request) ClientIP() string {
+	return ClientIP(c.RawRequest)
+}
+
+func (s *Server[T]) withClientIP() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := forwardedIP(r, s.trustedProxies)
+			if !ok {
+				h(w, r)
+				return
+			}
+
+			h(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		}
+	}
+}
+
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	ip, ok := r.Context().Value(clientIPKey{}).(netip.Addr)
+	return ip, ok
+}
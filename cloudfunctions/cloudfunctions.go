@@ -0,0 +1,60 @@
+package cloudfunctions
+
+import (
+	"net/http"
+)
+
+type Option func(*adapter)
+
+type adapter struct {
+	handler     http.Handler
+	path        string
+	healthCheck string
+}
+
+func WithPath(path string) Option {
+	return func(a *adapter) {
+		a.path = path
+	}
+}
+
+func WithHealthCheck(path string) Option {
+	return func(a *adapter) {
+		a.healthCheck = path
+	}
+}
+
+func Handler(handler http.Handler, options ...Option) http.HandlerFunc {
+	a := &adapter{
+		handler:     handler,
+		path:        "/",
+		healthCheck: "/healthz",
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	return a.serve
+}
+
+func (a *adapter) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		switch r.URL.Path {
+		case a.healthCheck:
+			w.WriteHeader(http.StatusOK)
+			return
+		case "/favicon.ico", "/robots.txt":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	if r.URL.Path != a.path {
+		r = r.Clone(r.Context())
+		r.URL.Path = a.path
+		r.URL.RawPath = ""
+	}
+
+	a.handler.ServeHTTP(w, r)
+}
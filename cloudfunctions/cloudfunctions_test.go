@@ -0,0 +1,51 @@
+package cloudfunctions_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martinrue/cadet"
+	"github.com/martinrue/cadet/cloudfunctions"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{Path: "/api"}, "")
+	server.Command("ping", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text("pong")
+	})
+
+	handler := cloudfunctions.Handler(server, cloudfunctions.WithPath("/api"))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		handler(recorder, req)
+		return recorder
+	}
+
+	for _, path := range []string{"/", "/my-function", "/api"} {
+		resp := request(http.MethodPost, path, `{"name":"ping"}`)
+		assertEqual(t, resp.Code, http.StatusOK)
+		assertEqual(t, resp.Body.String(), "pong")
+	}
+
+	resp := request(http.MethodGet, "/healthz", "")
+	assertEqual(t, resp.Code, http.StatusOK)
+
+	resp = request(http.MethodGet, "/favicon.ico", "")
+	assertEqual(t, resp.Code, http.StatusNotFound)
+
+	resp = request(http.MethodGet, "/", "")
+	assertEqual(t, resp.Code, http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,170 @@
+package cadet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type Codec interface {
+	Decode(r *http.Request) (*Command, error)
+	Encode(w http.ResponseWriter, v any) error
+}
+
+type JSONCodec struct {
+	DisallowUnknownFields bool
+}
+
+type MultipartCodec struct{}
+
+type FormCodec struct{}
+
+type queryCodec struct{}
+
+type codecKey struct{}
+
+func (c JSONCodec) Decode(r *http.Request) (*Command, error) {
+	defer r.Body.Close()
+
+	decoder := getDecoder(r.Body, c.DisallowUnknownFields)
+
+	command := newCommand()
+	if err := decoder.decode(command); err != nil {
+		releaseCommand(command)
+		return nil, err
+	}
+
+	putDecoder(decoder)
+
+	return command, nil
+}
+
+func (JSONCodec) Encode(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (MultipartCodec) Decode(r *http.Request) (*Command, error) {
+	body := r.FormValue("command")
+	if body == "" {
+		return nil, errors.New("no JSON payload found in multipart request")
+	}
+
+	command := &Command{}
+	if err := json.Unmarshal([]byte(body), command); err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+func (MultipartCodec) Encode(w http.ResponseWriter, v any) error {
+	return errors.New("multipart responses are not supported")
+}
+
+func (FormCodec) Decode(r *http.Request) (*Command, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	command := &Command{Name: r.PostForm.Get("name")}
+	if command.Name == "" {
+		return nil, errors.New("no command name found in form request")
+	}
+
+	if data := r.PostForm.Get("data"); data != "" {
+		if !json.Valid([]byte(data)) {
+			return nil, errors.New("form data field is not valid JSON")
+		}
+
+		command.Data = json.RawMessage(data)
+	}
+
+	return command, nil
+}
+
+func (FormCodec) Encode(w http.ResponseWriter, v any) error {
+	return errors.New("form responses are not supported")
+}
+
+func (queryCodec) Decode(r *http.Request) (*Command, error) {
+	query := r.URL.Query()
+
+	command := &Command{Name: query.Get("name")}
+	if command.Name == "" {
+		return nil, errors.New("no command name found in query string")
+	}
+
+	if data := query.Get("data"); data != "" {
+		if !json.Valid([]byte(data)) {
+			return nil, errors.New("query data parameter is not valid JSON")
+		}
+
+		command.Data = json.RawMessage(data)
+	}
+
+	return command, nil
+}
+
+func (queryCodec) Encode(w http.ResponseWriter, v any) error {
+	return errors.New("query responses are not supported")
+}
+
+func (s *Server[T]) RegisterCodec(mediaType string, codec Codec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.codecs[strings.ToLower(mediaType)] = codec
+}
+
+func (s *Server[T]) getCodec(r *http.Request) Codec {
+	if s.allowGET && r.Method == http.MethodGet {
+		return queryCodec{}
+	}
+
+	contentType, ok := mediaType(r.Header.Get("Content-Type"))
+	if !ok {
+		return nil
+	}
+
+	return s.lookupCodec(contentType)
+}
+
+func (s *Server[T]) lookupCodec(mediaType string) Codec {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.codecs[strings.ToLower(mediaType)]
+}
+
+func (s *Server[T]) withCodec() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), codecKey{}, s.getCodec(r))
+			ctx = context.WithValue(ctx, codecsKey{}, s.lookupCodec)
+			ctx = context.WithValue(ctx, commandKey{}, &parsedCommand{})
+			ctx = context.WithValue(ctx, dispatcherKey{}, dispatcher(s))
+
+			h(w, r.WithContext(ctx))
+		}
+	}
+}
+
+func codecFrom(r *http.Request) Codec {
+	codec, _ := r.Context().Value(codecKey{}).(Codec)
+	return codec
+}
+
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		"application/json":                  JSONCodec{},
+		"multipart/form-data":               MultipartCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+		"application/msgpack":               MsgPackCodec{},
+		"application/x-msgpack":             MsgPackCodec{},
+		"application/vnd.msgpack":           MsgPackCodec{},
+		"application/cbor":                  CBORCodec{},
+	}
+}
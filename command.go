@@ -0,0 +1,106 @@
+package cadet
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+type commandKey struct{}
+
+type parsedCommand struct {
+	parsed  bool
+	name    string
+	command *Command
+	err     error
+}
+
+func CommandName(r *http.Request) string {
+	return peekCommandName(r)
+}
+
+func parsedFrom(r *http.Request) *parsedCommand {
+	parsed, _ := r.Context().Value(commandKey{}).(*parsedCommand)
+	return parsed
+}
+
+func (p *parsedCommand) store(command *Command, err error) {
+	p.parsed = true
+	p.command = command
+	p.err = err
+
+	if command != nil {
+		p.name = command.Name
+	}
+}
+
+func (p *parsedCommand) release() {
+	if p != nil {
+		p.command = nil
+	}
+}
+
+func peekCommandName(r *http.Request) string {
+	if parsed := parsedFrom(r); parsed != nil && parsed.parsed {
+		return parsed.name
+	}
+
+	command := peekCommand(r)
+	if command == nil {
+		return ""
+	}
+
+	return command.Name
+}
+
+func peekCommand(r *http.Request) *Command {
+	parsed := parsedFrom(r)
+	if parsed != nil && parsed.parsed {
+		return parsed.command
+	}
+
+	command, err := readCommand(r)
+	if parsed != nil {
+		parsed.store(command, err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return command
+}
+
+func readCommand(r *http.Request) (*Command, error) {
+	codec := codecFrom(r)
+	if codec == nil {
+		return nil, ErrInvalidCommand
+	}
+
+	if _, ok := codec.(MultipartCodec); ok {
+		return codec.Decode(r)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+
+	if err != nil {
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), &errorReader{err}))
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	defer func() {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}()
+
+	return codec.Decode(r)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e *errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
@@ -0,0 +1,107 @@
+package cadet
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowOriginFunc  func(origin string) bool
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func CORS(options CORSOptions) Middleware {
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = []string{http.MethodPost}
+	}
+
+	methods := strings.Join(options.AllowedMethods, ", ")
+	headers := strings.Join(options.AllowedHeaders, ", ")
+	exposed := strings.Join(options.ExposedHeaders, ", ")
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if !preflight {
+				if origin != "" {
+					w.Header().Add("Vary", "Origin")
+
+					if options.allowsOrigin(origin) {
+						options.setOrigin(w.Header(), origin)
+
+						if exposed != "" {
+							w.Header().Set("Access-Control-Expose-Headers", exposed)
+						}
+					}
+				}
+
+				h(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+
+			if origin == "" || !options.allowsOrigin(origin) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			options.setOrigin(header, origin)
+			header.Set("Access-Control-Allow-Methods", methods)
+
+			if headers != "" {
+				header.Set("Access-Control-Allow-Headers", headers)
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+
+			if options.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func (o *CORSOptions) allowsOrigin(origin string) bool {
+	if len(o.AllowedOrigins) == 0 && o.AllowOriginFunc == nil {
+		return !o.AllowCredentials
+	}
+
+	if o.AllowOriginFunc != nil && o.AllowOriginFunc(origin) {
+		return true
+	}
+
+	return slices.ContainsFunc(o.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" && !o.AllowCredentials || strings.EqualFold(allowed, origin)
+	})
+}
+
+func (o *CORSOptions) setOrigin(header http.Header, origin string) {
+	if o.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+		return
+	}
+
+	if o.AllowOriginFunc == nil && (len(o.AllowedOrigins) == 0 || slices.Contains(o.AllowedOrigins, "*")) {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+}
@@ -0,0 +1,85 @@
+package cadet
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+type dispatcherKey struct{}
+
+type dispatcher interface {
+	dispatches(r *http.Request) bool
+}
+
+type CSRFOptions struct {
+	CookieName string
+	HeaderName string
+	Secure     bool
+}
+
+func CSRF(options CSRFOptions) Middleware {
+	if options.CookieName == "" {
+		options.CookieName = "csrf_token"
+	}
+
+	if options.HeaderName == "" {
+		options.HeaderName = "X-CSRF-Token"
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token := ""
+			if cookie, err := r.Cookie(options.CookieName); err == nil {
+				token = cookie.Value
+			}
+
+			if token == "" {
+				http.SetCookie(w, &http.Cookie{
+					Name:     options.CookieName,
+					Value:    newCSRFToken(),
+					Path:     "/",
+					Secure:   options.Secure,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			if csrfExempt(r) {
+				h(w, r)
+				return
+			}
+
+			header := r.Header.Get(options.HeaderName)
+			if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+				Error(http.StatusForbidden, "invalid CSRF token")(w)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+}
+
+func csrfExempt(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if !dispatched(r) {
+			return true
+		}
+	}
+
+	return len(r.Cookies()) == 0 || r.Header.Get("Authorization") != ""
+}
+
+func dispatched(r *http.Request) bool {
+	d, ok := r.Context().Value(dispatcherKey{}).(dispatcher)
+	return ok && d.dispatches(r)
+}
+
+func newCSRFToken() string {
+	token := make([]byte, 32)
+	rand.Read(token)
+
+	return base64.RawURLEncoding.EncodeToString(token)
+}
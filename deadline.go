@@ -0,0 +1,40 @@
+package cadet
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+func requestDeadline(r *http.Request) (time.Time, bool) {
+	if value := r.Header.Get("X-Request-Deadline"); value != "" {
+		if deadline, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return deadline, true
+		}
+	}
+
+	if value := r.Header.Get("Grpc-Timeout"); len(value) > 1 && len(value) <= 9 {
+		unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+		if !ok {
+			return time.Time{}, false
+		}
+
+		amount, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		if err != nil || amount < 0 {
+			return time.Time{}, false
+		}
+
+		return time.Now().Add(time.Duration(amount) * unit), true
+	}
+
+	return time.Time{}, false
+}
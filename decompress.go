@@ -0,0 +1,79 @@
+package cadet
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(data []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := l.reader.Read(probe[:]); n > 0 {
+			return 0, errBodyTooLarge
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(data)) > l.remaining {
+		data = data[:l.remaining]
+	}
+
+	n, err := l.reader.Read(data)
+	l.remaining -= int64(n)
+
+	return n, err
+}
+
+type decompressedBody struct {
+	io.Reader
+	body io.Closer
+	gzip *gzip.Reader
+}
+
+func (d *decompressedBody) Close() error {
+	d.gzip.Close()
+	return d.body.Close()
+}
+
+func (s *Server[T]) withDecompression() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+			switch encoding {
+			case "", "identity":
+				h(w, r)
+				return
+			case "gzip", "x-gzip":
+			default:
+				s.protocolError(w, r, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, encoding), s.unsupported)
+				return
+			}
+
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				s.protocolError(w, r, fmt.Errorf("%w: %w", ErrInvalidCommand, err), s.invalidCommand)
+				return
+			}
+
+			r.Body = &decompressedBody{&limitedReader{reader, s.maxDecompressed}, r.Body, reader}
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+
+			h(w, r)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const docsPath = "__docs"
+
+type docsPage struct {
+	Endpoint    string
+	Commands    []docsCommand
+	Definitions string
+}
+
+type docsCommand struct {
+	Name        string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	Input       string
+	Output      string
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Commands</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; color: #222; }
+section { border: 1px solid #ddd; border-radius: 6px; padding: 1rem; margin-bottom: 1rem; }
+h2 { font-size: 1.1rem; margin: 0 0 .5rem; font-family: monospace; }
+.tag { background: #eef; border-radius: 3px; padding: 0 .4rem; font-size: .8rem; margin-right: .3rem; }
+.deprecated { background: #fdd; border-radius: 3px; padding: 0 .4rem; font-size: .8rem; }
+pre, textarea { font-family: monospace; font-size: .85rem; background: #f7f7f7; padding: .5rem; }
+textarea { width: 100%; box-sizing: border-box; min-height: 5rem; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>Commands</h1>
+{{range .Commands}}
+<section>
+<h2>{{.Name}}</h2>
+{{if .Deprecated}}<span class="deprecated">deprecated</span>{{end}}
+{{range .Tags}}<span class="tag">{{.}}</span>{{end}}
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Input}}<details><summary>Input</summary><pre>{{.Input}}</pre></details>{{end}}
+{{if .Output}}<details><summary>Output</summary><pre>{{.Output}}</pre></details>{{end}}
+<form data-command="{{.Name}}">
+<textarea name="data">{}</textarea>
+<button type="submit">Invoke</button>
+<pre class="result" hidden></pre>
+</form>
+</section>
+{{end}}
+{{if .Definitions}}<details><summary>Definitions</summary><pre>{{.Definitions}}</pre></details>{{end}}
+<script>
+const endpoint = {{.Endpoint}};
+document.querySelectorAll("form[data-command]").forEach(form => {
+  form.addEventListener("submit", async event => {
+    event.preventDefault();
+    const result = form.querySelector(".result");
+    result.hidden = false;
+    try {
+      const data = JSON.parse(form.data.value || "null");
+      const response = await fetch(endpoint, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ name: form.dataset.command, data }),
+      });
+      result.textContent = response.status + " " + response.statusText + "\n\n" + await response.text();
+    } catch (err) {
+      result.textContent = err.toString();
+    }
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+func (s *Server[T]) docsPattern() string {
+	return path.Join(s.path, docsPath)
+}
+
+func (s *Server[T]) docs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	names, commands := s.sortedCommands()
+
+	generator := newSchemaGenerator("#/$defs/")
+	page := &docsPage{Endpoint: "./"}
+
+	if s.path != "/" {
+		page.Endpoint = "../" + path.Base(s.path)
+	}
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		options := commands[i].options
+		command := docsCommand{
+			Name:        name,
+			Description: options.meta.Description,
+			Tags:        options.meta.Tags,
+			Deprecated:  options.meta.Deprecated,
+		}
+
+		if options.input != nil {
+			command.Input = indentJSON(generator.schemaOf(options.input))
+		}
+
+		if options.output != nil {
+			command.Output = indentJSON(generator.schemaOf(options.output))
+		}
+
+		page.Commands = append(page.Commands, command)
+	}
+
+	if len(generator.definitions) > 0 {
+		page.Definitions = indentJSON(generator.definitions)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := docsTemplate.Execute(buffer, page); err != nil {
+		s.logger.Error("failed to render docs", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buffer.Bytes())
+}
+
+func indentJSON(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
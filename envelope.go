@@ -0,0 +1,133 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type envelopeWriter struct {
+	http.ResponseWriter
+	status  int
+	wrap    bool
+	decided bool
+	body    bytes.Buffer
+}
+
+func (w *envelopeWriter) WriteHeader(status int) {
+	if w.decided {
+		return
+	}
+
+	w.status = status
+	w.decided = true
+	w.wrap = isEnvelopeType(w.Header().Get("Content-Type"))
+
+	if !w.wrap {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.wrap {
+		return w.body.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *envelopeWriter) Flush() {
+	if w.decided && w.wrap {
+		return
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *envelopeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *envelopeWriter) finish() {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.wrap {
+		return
+	}
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	if !bodyAllowed(w.status) || len(body) > 0 && !json.Valid(body) {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	body = envelope(w.status, body)
+
+	if w.Header().Get("Deprecation") != "" {
+		body = append(body[:len(body)-2], `,"warning":"command is deprecated"}`+"\n"...)
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Del("Content-Length")
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+func envelope(status int, body []byte) []byte {
+	if status < http.StatusBadRequest {
+		if len(body) == 0 {
+			return []byte(`{"ok":true}` + "\n")
+		}
+
+		return append(append([]byte(`{"ok":true,"data":`), body...), "}\n"...)
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil {
+		if _, ok := fields["error"]; ok {
+			return append(append([]byte(`{"ok":false,`), bytes.TrimSpace(body[1:])...), '\n')
+		}
+	}
+
+	if len(body) == 0 {
+		body, _ = json.Marshal(http.StatusText(status))
+	}
+
+	return append(append([]byte(`{"ok":false,"error":`), body...), "}\n"...)
+}
+
+func isEnvelopeType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	value, ok := mediaType(contentType)
+	if !ok {
+		return false
+	}
+
+	return value == "application/json" || strings.HasSuffix(value, "+json")
+}
+
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,56 @@
+package cadet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func Fail(err error) Response {
+	return func(w http.ResponseWriter) {
+		if rw, ok := w.(*responseWriter); ok && rw.fail != nil {
+			if response := rw.fail(err); response != nil {
+				response(w)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (s *Server[T]) respondError(w http.ResponseWriter, r *http.Request, request *Request, err error, fallback Response) {
+	if s.onError != nil {
+		if request == nil {
+			request = &Request{RawResponse: w, RawRequest: r}
+		}
+
+		if response := s.onError(request, err); response != nil {
+			response(&responseWriter{w, r, nil})
+			return
+		}
+	}
+
+	fallback(&responseWriter{w, r, nil})
+}
+
+func (s *Server[T]) protocolError(w http.ResponseWriter, r *http.Request, err error, fallback Response) {
+	if s.envelope {
+		writer := &envelopeWriter{ResponseWriter: w}
+		defer writer.finish()
+		w = writer
+	}
+
+	s.respondError(w, r, nil, err, fallback)
+}
@@ -0,0 +1,60 @@
+package cadet
+
+import "net/http"
+
+type Group[T any] struct {
+	server       *Server[T]
+	parent       *Group[T]
+	prefix       string
+	middleware   []Middleware
+	commandChain []CommandMiddleware[T]
+}
+
+func (g *Group[T]) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+func (g *Group[T]) Group(name string, middleware ...Middleware) *Group[T] {
+	return &Group[T]{
+		server:     g.server,
+		parent:     g,
+		prefix:     g.commandName(name),
+		middleware: middleware,
+	}
+}
+
+func (g *Group[T]) Command(name string, handler func(r *Request, context T) Response, options ...CommandOption) {
+	g.server.register(g.commandName(name), handler, g, options)
+}
+
+func (g *Group[T]) Commands(args ...any) error {
+	return registerCommands(func(name string, handler func(*Request, T) Response) {
+		g.server.register(g.commandName(name), handler, g, nil)
+	}, args...)
+}
+
+func (g *Group[T]) CommandMap(commands map[string]func(r *Request, context T) Response, options ...CommandOption) {
+	for name, handler := range commands {
+		g.server.register(g.commandName(name), handler, g, options)
+	}
+}
+
+func (g *Group[T]) commandName(name string) string {
+	if g.prefix == "" {
+		return name
+	}
+
+	return g.prefix + "." + name
+}
+
+func (g *Group[T]) wrap(h http.HandlerFunc) http.HandlerFunc {
+	if g == nil {
+		return h
+	}
+
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		h = g.middleware[i](h)
+	}
+
+	return g.parent.wrap(h)
+}
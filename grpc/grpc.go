@@ -0,0 +1,155 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/martinrue/cadet/internal/transport"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const ServiceName = "cadet.Cadet"
+
+type Option func(*Service)
+
+type Service struct {
+	handler http.Handler
+	path    string
+}
+
+type invoker interface {
+	Invoke(ctx context.Context, in *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+}
+
+var serviceDesc = grpclib.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*invoker)(nil),
+	Methods: []grpclib.MethodDesc{
+		{MethodName: "Invoke", Handler: invokeHandler},
+	},
+	Metadata: "cadet.proto",
+}
+
+func WithPath(path string) Option {
+	return func(s *Service) {
+		s.path = path
+	}
+}
+
+func New(handler http.Handler, options ...Option) *Service {
+	service := &Service{
+		handler: handler,
+		path:    "/",
+	}
+
+	for _, option := range options {
+		option(service)
+	}
+
+	return service
+}
+
+func (s *Service) Register(registrar grpclib.ServiceRegistrar) {
+	registrar.RegisterService(&serviceDesc, s)
+}
+
+func (s *Service) Invoke(ctx context.Context, in *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	req, err := transport.NewRequest(ctx, s.path, in.GetValue())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || strings.HasSuffix(key, "-bin") {
+				continue
+			}
+
+			if strings.EqualFold(key, "content-type") {
+				continue
+			}
+
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	rec := transport.Serve(s.handler, req)
+
+	if contentType := rec.Header().Get("Content-Type"); contentType != "" {
+		grpclib.SetHeader(ctx, metadata.Pairs("cadet-content-type", contentType))
+	}
+
+	if rec.Status >= http.StatusBadRequest {
+		message := strings.TrimSpace(rec.Body.String())
+		if message == "" {
+			message = http.StatusText(rec.Status)
+		}
+
+		return nil, status.Error(statusCode(rec.Status), message)
+	}
+
+	return wrapperspb.Bytes(rec.Body.Bytes()), nil
+}
+
+func invokeHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpclib.UnaryServerInterceptor) (any, error) {
+	in := &wrapperspb.BytesValue{}
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+
+	if interceptor == nil {
+		return srv.(invoker).Invoke(ctx, in)
+	}
+
+	info := &grpclib.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + ServiceName + "/Invoke",
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(invoker).Invoke(ctx, req.(*wrapperspb.BytesValue))
+	}
+
+	return interceptor(ctx, in, info, handler)
+}
+
+func statusCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	if status >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+
+	return codes.Unknown
+}
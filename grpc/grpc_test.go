@@ -0,0 +1,92 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/martinrue/cadet"
+	cadetgrpc "github.com/martinrue/cadet/grpc"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func createConnection(t *testing.T, service *cadetgrpc.Service) *grpclib.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpclib.NewServer()
+	service.Register(server)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet", grpclib.WithContextDialer(dialer), grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	assertNoError(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestInvoke(t *testing.T) {
+	server := cadet.NewServer(&cadet.Config{Path: "/api"}, "")
+	server.Command("greet", func(r *cadet.Request, ctx string) cadet.Response {
+		data := &struct {
+			Name string `json:"name"`
+		}{}
+
+		if err := r.ReadCommand(data); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Text("hello " + data.Name + " from " + r.RawRequest.Header.Get("X-Caller"))
+	})
+
+	conn := createConnection(t, cadetgrpc.New(server, cadetgrpc.WithPath("/api")))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-caller", "billing")
+	in := wrapperspb.Bytes([]byte(`{"name":"greet","data":{"name":"cadet"}}`))
+	out := &wrapperspb.BytesValue{}
+	header := metadata.MD{}
+
+	err := conn.Invoke(ctx, "/cadet.Cadet/Invoke", in, out, grpclib.Header(&header))
+	assertNoError(t, err)
+	assertEqual(t, string(out.Value), "hello cadet from billing")
+	assertEqual(t, header.Get("cadet-content-type")[0], "text/plain; charset=utf-8")
+
+	in = wrapperspb.Bytes([]byte(`{"name":"missing"}`))
+	err = conn.Invoke(ctx, "/cadet.Cadet/Invoke", in, out)
+	assertEqual(t, status.Code(err), codes.NotFound)
+
+	in = wrapperspb.Bytes([]byte(`{"name":"greet","data":[]}`))
+	err = conn.Invoke(ctx, "/cadet.Cadet/Invoke", in, out)
+	assertEqual(t, status.Code(err), codes.InvalidArgument)
+}
@@ -0,0 +1,96 @@
+package cadet
+
+import "slices"
+
+type CommandHandler[T any] func(r *Request, context T) Response
+
+type CommandMiddleware[T any] func(CommandHandler[T]) CommandHandler[T]
+
+func (s *Server[T]) UseCommand(middleware ...CommandMiddleware[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commandChain = append(s.commandChain, middleware...)
+}
+
+func (g *Group[T]) UseCommand(middleware ...CommandMiddleware[T]) {
+	g.server.mu.Lock()
+	defer g.server.mu.Unlock()
+
+	g.commandChain = append(g.commandChain, middleware...)
+}
+
+func (s *Server[T]) wrapCommand(handler *command[T]) CommandHandler[T] {
+	s.mu.RLock()
+	chain := slices.Clone(s.commandChain)
+	groups := handler.group.commandChains()
+	s.mu.RUnlock()
+
+	h := s.intercept(handler.handler)
+	for _, middleware := range groups {
+		h = wrapCommand(middleware, h)
+	}
+
+	return wrapCommand(chain, h)
+}
+
+func (g *Group[T]) commandChains() [][]CommandMiddleware[T] {
+	chains := [][]CommandMiddleware[T]{}
+	for ; g != nil; g = g.parent {
+		chains = append(chains, slices.Clone(g.commandChain))
+	}
+
+	return chains
+}
+
+func wrapCommand[T any](middleware []CommandMiddleware[T], h CommandHandler[T]) CommandHandler[T] {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	return h
+}
+
+func (s *Server[T]) Before(hook func(r *Request, context T) Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.before = append(s.before, hook)
+}
+
+func (s *Server[T]) After(hook func(r *Request, context T, response Response) Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.after = append(s.after, hook)
+}
+
+func (s *Server[T]) intercept(h CommandHandler[T]) CommandHandler[T] {
+	s.mu.RLock()
+	before, after := slices.Clone(s.before), slices.Clone(s.after)
+	s.mu.RUnlock()
+
+	if len(before) == 0 && len(after) == 0 {
+		return h
+	}
+
+	return func(r *Request, context T) Response {
+		var response Response
+
+		for _, hook := range before {
+			if response = hook(r, context); response != nil {
+				break
+			}
+		}
+
+		if response == nil {
+			response = h(r, context)
+		}
+
+		for _, hook := range after {
+			response = hook(r, context, response)
+		}
+
+		return response
+	}
+}
@@ -0,0 +1,37 @@
+package cadet
+
+import (
+	"net/http"
+	"time"
+)
+
+type Hooks struct {
+	OnRequest     func(r *Request)
+	OnResponse    func(r *Request, response ResponseDetails, duration time.Duration)
+	OnPanic       func(r *Request, recovered any, stack []byte)
+	OnDecodeError func(r *http.Request, err error)
+}
+
+func (h *Hooks) request(r *Request) {
+	if h != nil && h.OnRequest != nil {
+		h.OnRequest(r)
+	}
+}
+
+func (h *Hooks) response(r *Request, w *recordingWriter, start time.Time) {
+	if h != nil && h.OnResponse != nil {
+		h.OnResponse(r, ResponseDetails{Status: w.Status(), Size: w.size, Written: w.status != 0}, time.Since(start))
+	}
+}
+
+func (h *Hooks) panic(r *Request, recovered any, stack []byte) {
+	if h != nil && h.OnPanic != nil {
+		h.OnPanic(r, recovered, stack)
+	}
+}
+
+func (h *Hooks) decodeError(r *http.Request, err error) {
+	if h != nil && h.OnDecodeError != nil {
+		h.OnDecodeError(r, err)
+	}
+}
@@ -0,0 +1,72 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/martinrue/cadet"
+	http3lib "github.com/quic-go/quic-go/http3"
+)
+
+type Option func(*Server)
+
+type Server struct {
+	server *http3lib.Server
+}
+
+func WithTLSConfig(config *tls.Config) Option {
+	return func(s *Server) {
+		s.server.TLSConfig = http3lib.ConfigureTLSConfig(config)
+	}
+}
+
+func WithPort(port int) Option {
+	return func(s *Server) {
+		s.server.Port = port
+	}
+}
+
+func New(handler http.Handler, addr string, options ...Option) *Server {
+	server := &Server{
+		server: &http3lib.Server{
+			Addr:    addr,
+			Handler: handler,
+		},
+	}
+
+	for _, option := range options {
+		option(server)
+	}
+
+	return server
+}
+
+func (s *Server) ListenAndServe() error {
+	return s.server.ListenAndServe()
+}
+
+func (s *Server) ListenAndServeTLS(certFile string, keyFile string) error {
+	return s.server.ListenAndServeTLS(certFile, keyFile)
+}
+
+func (s *Server) Serve(conn net.PacketConn) error {
+	return s.server.Serve(conn)
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) AltSvc() cadet.Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor < 3 {
+				s.server.SetQUICHeaders(w.Header())
+			}
+
+			h(w, r)
+		}
+	}
+}
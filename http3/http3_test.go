@@ -0,0 +1,141 @@
+package http3_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinrue/cadet"
+	cadethttp3 "github.com/martinrue/cadet/http3"
+	http3lib "github.com/quic-go/quic-go/http3"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func createCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assertNoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assertNoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func createServer() *cadet.Server[string] {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("proto", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Text(r.RawRequest.Proto)
+	})
+
+	return server
+}
+
+func TestServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assertNoError(t, err)
+	defer conn.Close()
+
+	addr := conn.LocalAddr().String()
+	_, port, _ := net.SplitHostPort(addr)
+
+	config := &tls.Config{Certificates: []tls.Certificate{createCertificate(t)}}
+
+	server := createServer()
+	quic := cadethttp3.New(server, addr, cadethttp3.WithTLSConfig(config))
+	server.Use(quic.AltSvc())
+
+	go quic.Serve(conn)
+	defer quic.Shutdown(context.Background())
+
+	transport := &http3lib.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer transport.Close()
+
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post("https://"+addr, "application/json", strings.NewReader(`{"name":"proto"}`))
+	assertNoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assertNoError(t, err)
+	assertEqual(t, resp.StatusCode, http.StatusOK)
+	assertEqual(t, resp.Header.Get("Alt-Svc"), "")
+	assertEqual(t, string(data), "HTTP/3.0")
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"proto"}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.Handler().ServeHTTP(recorder, req)
+
+	assertEqual(t, recorder.Code, http.StatusOK)
+	assertEqual(t, recorder.Header().Get("Alt-Svc"), `h3=":`+port+`"; ma=2592000`)
+}
+
+func TestAltSvcPort(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assertNoError(t, err)
+	defer conn.Close()
+
+	config := &tls.Config{Certificates: []tls.Certificate{createCertificate(t)}}
+
+	server := createServer()
+	quic := cadethttp3.New(server, conn.LocalAddr().String(), cadethttp3.WithTLSConfig(config), cadethttp3.WithPort(443))
+	server.Use(quic.AltSvc())
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"proto"}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.Handler().ServeHTTP(recorder, req)
+
+	assertEqual(t, recorder.Header().Get("Alt-Svc"), "")
+
+	go quic.Serve(conn)
+	defer quic.Shutdown(context.Background())
+
+	for recorder.Header().Get("Alt-Svc") == "" {
+		time.Sleep(time.Millisecond)
+
+		recorder = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"proto"}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(recorder, req)
+	}
+
+	assertEqual(t, recorder.Header().Get("Alt-Svc"), `h3=":443"; ma=2592000`)
+}
@@ -0,0 +1,26 @@
+package observe
+
+import "context"
+
+type Observer interface {
+	Command(name string)
+	Error(err error)
+}
+
+type observerKey struct{}
+
+func With(ctx context.Context, observer Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+func Command(ctx context.Context, name string) {
+	if observer, ok := ctx.Value(observerKey{}).(Observer); ok {
+		observer.Command(name)
+	}
+}
+
+func Error(ctx context.Context, err error) {
+	if observer, ok := ctx.Value(observerKey{}).(Observer); ok {
+		observer.Error(err)
+	}
+}
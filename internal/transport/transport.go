@@ -0,0 +1,50 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+type Recorder struct {
+	Status int
+	Body   bytes.Buffer
+	header http.Header
+}
+
+func (r *Recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *Recorder) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+}
+
+func (r *Recorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.Body.Write(data)
+}
+
+func NewRequest(ctx context.Context, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+func Serve(handler http.Handler, req *http.Request) *Recorder {
+	recorder := &Recorder{header: http.Header{}}
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Status == 0 {
+		recorder.Status = http.StatusOK
+	}
+
+	return recorder
+}
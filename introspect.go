@@ -0,0 +1,49 @@
+package cadet
+
+import "strings"
+
+const introspectCommand = "__introspect"
+
+type introspection struct {
+	Commands    []introspectedCommand `json:"commands"`
+	Definitions map[string]any        `json:"$defs,omitempty"`
+}
+
+type introspectedCommand struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Deprecated  bool           `json:"deprecated,omitempty"`
+	Input       map[string]any `json:"input,omitempty"`
+	Output      map[string]any `json:"output,omitempty"`
+}
+
+func (s *Server[T]) introspect(r *Request, context T) Response {
+	names, commands := s.sortedCommands()
+
+	generator := newSchemaGenerator("#/$defs/")
+	result := &introspection{Commands: []introspectedCommand{}}
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		meta := commands[i].options.meta
+		command := introspectedCommand{Name: name, Description: meta.Description, Tags: meta.Tags, Deprecated: meta.Deprecated}
+
+		if commands[i].options.input != nil {
+			command.Input = generator.schemaOf(commands[i].options.input)
+		}
+
+		if commands[i].options.output != nil {
+			command.Output = generator.schemaOf(commands[i].options.output)
+		}
+
+		result.Commands = append(result.Commands, command)
+	}
+
+	result.Definitions = generator.definitions
+
+	return JSON(result)
+}
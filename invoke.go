@@ -0,0 +1,42 @@
+package cadet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/martinrue/cadet/internal/transport"
+)
+
+type Result struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (r Result) Decode(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+func (s *Server[T]) Invoke(ctx context.Context, name string, data any) (Result, error) {
+	if s.lookup(name) == nil && s.defaultCommand() == nil {
+		return Result{}, ErrCommandNotFound
+	}
+
+	body, err := json.Marshal(&struct {
+		Name string `json:"name"`
+		Data any    `json:"data,omitempty"`
+	}{name, data})
+	if err != nil {
+		return Result{}, err
+	}
+
+	req, err := transport.NewRequest(ctx, s.path, body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rec := transport.Serve(s, req)
+
+	return Result{rec.Status, rec.Header(), rec.Body.Bytes()}, nil
+}
@@ -0,0 +1,102 @@
+package cadet
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+func IPFilter(allow []string, deny []string, trustedProxies ...string) Middleware {
+	allowed := parsePrefixes(allow)
+	denied := parsePrefixes(deny)
+	trusted := parsePrefixes(trustedProxies)
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := clientAddr(r)
+			if len(trusted) > 0 || !ok {
+				ip, ok = forwardedIP(r, trusted)
+			}
+
+			if !ok || containsIP(denied, ip) || len(allowed) > 0 && !containsIP(allowed, ip) {
+				Error(http.StatusForbidden, "forbidden")(w)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+}
+
+func parsePrefixes(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				panic(fmt.Sprintf("cadet: invalid IP %q", value))
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			panic(fmt.Sprintf("cadet: invalid CIDR %q", value))
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes
+}
+
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func forwardedIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	ip, err := netip.ParseAddr(remoteIP(r))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	ip = ip.Unmap()
+	if !containsIP(trusted, ip) {
+		return ip, true
+	}
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		if real, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return real.Unmap(), true
+		}
+
+		return ip, true
+	}
+
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return ip, true
+		}
+
+		ip = hop.Unmap()
+		if !containsIP(trusted, ip) {
+			return ip, true
+		}
+	}
+
+	return ip, true
+}
@@ -0,0 +1,371 @@
+package cadet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/martinrue/cadet/internal/transport"
+)
+
+const (
+	jobStatusCommand = "__job_status"
+	jobResultCommand = "__job_result"
+	jobRetention     = time.Hour
+	jobLease         = time.Minute
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+)
+
+type Job struct {
+	ID       string          `json:"id"`
+	Command  string          `json:"command"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Status   JobStatus       `json:"status"`
+	Progress int             `json:"progress"`
+	Message  string          `json:"message,omitempty"`
+	Result   *CachedResponse `json:"result,omitempty"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+}
+
+type jobResponse struct {
+	ID       string    `json:"id"`
+	Command  string    `json:"command"`
+	Status   JobStatus `json:"status"`
+	Progress int       `json:"progress"`
+	Message  string    `json:"message,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+type JobStore interface {
+	Save(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id string) (*Job, bool, error)
+	Claim(ctx context.Context, id string, lease time.Duration) (bool, error)
+	Progress(ctx context.Context, id string, percent int, message string) error
+	Complete(ctx context.Context, id string, result *CachedResponse) error
+	List(ctx context.Context, status JobStatus) ([]*Job, error)
+}
+
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+type jobRequest struct {
+	ID string `json:"id"`
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]*Job)}
+}
+
+func (m *MemoryJobStore) Save(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	for id, existing := range m.jobs {
+		if existing.Status == JobCompleted && now.Sub(existing.Updated) > jobRetention {
+			delete(m.jobs, id)
+		}
+	}
+
+	copied := *job
+	m.jobs[job.ID] = &copied
+
+	return nil
+}
+
+func (m *MemoryJobStore) Get(ctx context.Context, id string) (*Job, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	copied := *job
+	return &copied, true, nil
+}
+
+func (m *MemoryJobStore) Claim(ctx context.Context, id string, lease time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return false, nil
+	}
+
+	expired := job.Status == JobRunning && time.Since(job.Updated) > lease
+	if job.Status != JobPending && !expired {
+		return false, nil
+	}
+
+	job.Status = JobRunning
+	job.Updated = time.Now()
+
+	return true, nil
+}
+
+func (m *MemoryJobStore) Progress(ctx context.Context, id string, percent int, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[id]; ok {
+		job.Progress = percent
+		job.Message = message
+		job.Updated = time.Now()
+	}
+
+	return nil
+}
+
+func (m *MemoryJobStore) Complete(ctx context.Context, id string, result *CachedResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[id]; ok {
+		job.Status = JobCompleted
+		job.Result = result
+		job.Updated = time.Now()
+	}
+
+	return nil
+}
+
+func (m *MemoryJobStore) List(ctx context.Context, status JobStatus) ([]*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := []*Job{}
+
+	for _, job := range m.jobs {
+		if job.Status == status {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (s *Server[T]) submitJob(w http.ResponseWriter, r *http.Request, handler *command[T], command *Command) {
+	now := time.Now()
+	job := &Job{ID: newRequestID(), Command: command.Name, Data: command.Data, Status: JobPending, Created: now, Updated: now}
+
+	if err := s.jobStore.Save(r.Context(), job); err != nil {
+		s.logger.Error("failed to save job", "command", command.Name, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	r = r.Clone(context.WithoutCancel(r.Context()))
+	r.Header.Del("X-Request-Deadline")
+	r.Header.Del("Grpc-Timeout")
+
+	if !s.startJob(job, handler, r) {
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJob(w, job)
+}
+
+func (s *Server[T]) ResumeJobs(ctx context.Context) error {
+	pending, err := s.jobStore.List(ctx, JobPending)
+	if err != nil {
+		return err
+	}
+
+	running, err := s.jobStore.List(ctx, JobRunning)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range append(pending, running...) {
+		r, err := transport.NewRequest(context.Background(), s.path, nil)
+		if err != nil {
+			return err
+		}
+
+		handler := s.lookup(job.Command)
+		if handler == nil {
+			if err := s.jobStore.Complete(ctx, job.ID, &CachedResponse{Status: http.StatusNotFound}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if job.Status == JobRunning {
+			if wait := time.Until(job.Updated.Add(s.jobLease)); wait > 0 {
+				go s.resumeJob(wait, job, handler, r)
+
+				continue
+			}
+		}
+
+		s.startJob(job, handler, r)
+	}
+
+	return nil
+}
+
+func (s *Server[T]) resumeJob(wait time.Duration, job *Job, handler *command[T], r *http.Request) {
+	timer := time.NewTimer(wait)
+
+	select {
+	case <-s.scheduler.Done():
+		timer.Stop()
+		return
+	case <-timer.C:
+	}
+
+	s.startJob(job, handler, r)
+}
+
+func (s *Server[T]) startJob(job *Job, handler *command[T], r *http.Request) bool {
+	if !s.tracker.begin() {
+		return false
+	}
+
+	go func() {
+		defer s.tracker.end()
+
+		ctx := r.Context()
+
+		claimed, err := s.jobStore.Claim(ctx, job.ID, s.jobLease)
+		if err != nil {
+			s.logger.Error("failed to claim job", "job", job.ID, "error", err)
+			return
+		}
+
+		if !claimed {
+			return
+		}
+
+		var mu sync.Mutex
+		percent, message := job.Progress, job.Message
+
+		progress := func(p int, m string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			percent, message = p, m
+			if err := s.jobStore.Progress(ctx, job.ID, p, m); err != nil {
+				s.logger.Error("failed to update job progress", "job", job.ID, "error", err)
+			}
+		}
+
+		heartbeat := time.NewTicker(s.jobLease / 3)
+		done, stopped := make(chan struct{}), make(chan struct{})
+
+		go func() {
+			defer close(stopped)
+
+			for {
+				select {
+				case <-heartbeat.C:
+					mu.Lock()
+					p, m := percent, message
+					mu.Unlock()
+
+					progress(p, m)
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		r = r.WithContext(context.WithValue(ctx, progressKey{}, progress))
+
+		rec := transport.Serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.runCommand(w, r, handler, &Command{Name: job.Command, Data: job.Data})
+		}), r)
+
+		heartbeat.Stop()
+		close(done)
+		<-stopped
+
+		if err := s.jobStore.Complete(ctx, job.ID, &CachedResponse{rec.Status, rec.Header(), rec.Body.Bytes()}); err != nil {
+			s.logger.Error("failed to complete job", "job", job.ID, "error", err)
+		}
+	}()
+
+	return true
+}
+
+func (s *Server[T]) findJob(r *Request) (*Job, Response) {
+	input := &jobRequest{}
+	if err := r.ReadCommand(input); err != nil || input.ID == "" {
+		return nil, Status(http.StatusUnprocessableEntity)
+	}
+
+	job, ok, err := s.jobStore.Get(r.RawRequest.Context(), input.ID)
+	if err != nil {
+		s.logger.Error("failed to get job", "job", input.ID, "error", err)
+		return nil, Status(http.StatusInternalServerError)
+	}
+
+	if !ok {
+		return nil, Status(http.StatusNotFound)
+	}
+
+	return job, nil
+}
+
+func (s *Server[T]) jobStatus(r *Request, context T) Response {
+	job, failed := s.findJob(r)
+	if failed != nil {
+		return failed
+	}
+
+	return JSON(newJobResponse(job))
+}
+
+func (s *Server[T]) jobResult(r *Request, context T) Response {
+	job, failed := s.findJob(r)
+	if failed != nil {
+		return failed
+	}
+
+	if job.Status != JobCompleted {
+		return func(w http.ResponseWriter) {
+			writeJob(w, job)
+		}
+	}
+
+	return job.Result.write
+}
+
+func writeJob(w http.ResponseWriter, job *Job) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+func newJobResponse(job *Job) *jobResponse {
+	return &jobResponse{
+		ID:       job.ID,
+		Command:  job.Command,
+		Status:   job.Status,
+		Progress: job.Progress,
+		Message:  job.Message,
+		Created:  job.Created,
+		Updated:  job.Updated,
+	}
+}
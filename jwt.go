@@ -0,0 +1,56 @@
+package cadet
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type JWTOptions struct {
+	Methods  []string
+	Audience string
+	Issuer   string
+	Leeway   time.Duration
+}
+
+func JWT(keyfunc jwt.Keyfunc, options JWTOptions) Middleware {
+	parserOptions := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(options.Leeway)}
+
+	if len(options.Methods) > 0 {
+		parserOptions = append(parserOptions, jwt.WithValidMethods(options.Methods))
+	}
+
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+
+	parser := jwt.NewParser(parserOptions...)
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "Bearer", "missing bearer token")
+				return
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := parser.ParseWithClaims(token, claims, keyfunc); err != nil {
+				unauthorized(w, `Bearer error="invalid_token"`, "invalid bearer token")
+				return
+			}
+
+			h(w, withPrincipal(r, claims))
+		}
+	}
+}
+
+func (c *Request) Claims() jwt.MapClaims {
+	claims, _ := c.Principal().(jwt.MapClaims)
+	return claims
+}
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinrue/cadet/internal/transport"
+	kafkalib "github.com/segmentio/kafka-go"
+)
+
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafkalib.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafkalib.Message) error
+}
+
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafkalib.Message) error
+}
+
+type Option func(*Consumer)
+
+type Consumer struct {
+	reader     Reader
+	handler    http.Handler
+	path       string
+	retries    int
+	backoff    time.Duration
+	deadLetter Writer
+}
+
+func WithPath(path string) Option {
+	return func(c *Consumer) {
+		c.path = path
+	}
+}
+
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Consumer) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+func WithDeadLetter(writer Writer) Option {
+	return func(c *Consumer) {
+		c.deadLetter = writer
+	}
+}
+
+func NewConsumer(reader Reader, handler http.Handler, options ...Option) *Consumer {
+	consumer := &Consumer{
+		reader:  reader,
+		handler: handler,
+		path:    "/",
+		backoff: time.Second,
+	}
+
+	for _, option := range options {
+		option(consumer)
+	}
+
+	return consumer
+}
+
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := c.process(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, msg kafkalib.Message) error {
+	status := 0
+
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.backoff):
+			}
+		}
+
+		rec, err := c.dispatch(ctx, msg)
+		if err != nil {
+			return err
+		}
+
+		status = rec.Status
+		if status < http.StatusBadRequest {
+			return nil
+		}
+
+		if status < http.StatusInternalServerError {
+			break
+		}
+	}
+
+	if c.deadLetter == nil {
+		return nil
+	}
+
+	headers := append(msg.Headers[:len(msg.Headers):len(msg.Headers)],
+		kafkalib.Header{Key: "cadet-status", Value: []byte(strconv.Itoa(status))},
+		kafkalib.Header{Key: "cadet-topic", Value: []byte(msg.Topic)},
+	)
+
+	return c.deadLetter.WriteMessages(ctx, kafkalib.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+}
+
+func (c *Consumer) dispatch(ctx context.Context, msg kafkalib.Message) (*transport.Recorder, error) {
+	req, err := transport.NewRequest(ctx, c.path, msg.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range msg.Headers {
+		if strings.EqualFold(header.Key, "Content-Type") {
+			continue
+		}
+
+		req.Header.Add(header.Key, string(header.Value))
+	}
+
+	rec := transport.Serve(c.handler, req)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return rec, nil
+}
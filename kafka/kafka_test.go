@@ -0,0 +1,123 @@
+package kafka_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/martinrue/cadet"
+	cadetkafka "github.com/martinrue/cadet/kafka"
+	kafkalib "github.com/segmentio/kafka-go"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+type reader struct {
+	messages  chan kafkalib.Message
+	committed []kafkalib.Message
+	cancel    context.CancelFunc
+}
+
+func (r *reader) FetchMessage(ctx context.Context) (kafkalib.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	default:
+		r.cancel()
+		return kafkalib.Message{}, context.Canceled
+	}
+}
+
+func (r *reader) CommitMessages(ctx context.Context, msgs ...kafkalib.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+type writer struct {
+	mu       sync.Mutex
+	messages []kafkalib.Message
+}
+
+func (w *writer) WriteMessages(ctx context.Context, msgs ...kafkalib.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func header(msg kafkalib.Message, key string) string {
+	for _, header := range msg.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+func TestConsumer(t *testing.T) {
+	attempts := map[string]int{}
+	handled := []string{}
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("record", func(r *cadet.Request, ctx string) cadet.Response {
+		text := ""
+		if err := r.ReadCommand(&text); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		attempts[text]++
+		if text == "flaky" && attempts[text] < 3 {
+			return cadet.Status(http.StatusServiceUnavailable)
+		}
+
+		if text == "broken" {
+			return cadet.Status(http.StatusInternalServerError)
+		}
+
+		handled = append(handled, text+r.RawRequest.Header.Get("X-Tenant"))
+		return cadet.Status(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &reader{messages: make(chan kafkalib.Message, 4), cancel: cancel}
+	r.messages <- kafkalib.Message{Topic: "commands", Value: []byte(`{"name":"record","data":"one"}`), Headers: []kafkalib.Header{{Key: "X-Tenant", Value: []byte("-acme")}}}
+	r.messages <- kafkalib.Message{Topic: "commands", Value: []byte(`{"name":"record","data":"flaky"}`)}
+	r.messages <- kafkalib.Message{Topic: "commands", Value: []byte(`{"name":"record","data":"broken"}`)}
+	r.messages <- kafkalib.Message{Topic: "commands", Value: []byte(`{"name":"missing"}`)}
+
+	w := &writer{}
+
+	consumer := cadetkafka.NewConsumer(r, server, cadetkafka.WithRetries(2, 0), cadetkafka.WithDeadLetter(w))
+	assertNoError(t, consumer.Run(ctx))
+
+	assertEqual(t, len(r.committed), 4)
+	assertEqual(t, len(handled), 2)
+	assertEqual(t, handled[0], "one-acme")
+	assertEqual(t, handled[1], "flaky")
+	assertEqual(t, attempts["flaky"], 3)
+	assertEqual(t, attempts["broken"], 3)
+
+	assertEqual(t, len(w.messages), 2)
+	assertEqual(t, string(w.messages[0].Value), `{"name":"record","data":"broken"}`)
+	assertEqual(t, header(w.messages[0], "cadet-status"), "500")
+	assertEqual(t, header(w.messages[0], "cadet-topic"), "commands")
+	assertEqual(t, header(w.messages[1], "cadet-status"), "404")
+}
@@ -0,0 +1,106 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type DecodeLimits struct {
+	MaxBodySize    int64
+	MaxDataSize    int64
+	MaxDepth       int
+	MaxArrayLength int
+}
+
+func (l *DecodeLimits) check(data json.RawMessage) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.MaxDataSize > 0 && int64(len(data)) > l.MaxDataSize {
+		return errBodyTooLarge
+	}
+
+	if l.MaxDepth <= 0 && l.MaxArrayLength <= 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	lengths := []int{}
+
+	count := func() error {
+		if len(lengths) == 0 || lengths[len(lengths)-1] < 0 {
+			return nil
+		}
+
+		lengths[len(lengths)-1]++
+
+		if l.MaxArrayLength > 0 && lengths[len(lengths)-1] > l.MaxArrayLength {
+			return errors.New("command data array exceeds maximum length")
+		}
+
+		return nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('['), json.Delim('{'):
+			if err := count(); err != nil {
+				return err
+			}
+
+			if l.MaxDepth > 0 && len(lengths) >= l.MaxDepth {
+				return errors.New("command data exceeds maximum nesting depth")
+			}
+
+			if token == json.Delim('[') {
+				lengths = append(lengths, 0)
+			} else {
+				lengths = append(lengths, -1)
+			}
+		case json.Delim(']'), json.Delim('}'):
+			lengths = lengths[:len(lengths)-1]
+		default:
+			if err := count(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func (s *Server[T]) withBodyLimit() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if s.limits == nil || s.limits.MaxBodySize <= 0 {
+				h(w, r)
+				return
+			}
+
+			if r.ContentLength > s.limits.MaxBodySize {
+				s.protocolError(w, r, fmt.Errorf("%w: %w", ErrInvalidCommand, errBodyTooLarge), Status(http.StatusRequestEntityTooLarge))
+				return
+			}
+
+			r.Body = &limitedBody{&limitedReader{r.Body, s.limits.MaxBodySize}, r.Body}
+			h(w, r)
+		}
+	}
+}
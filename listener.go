@@ -0,0 +1,118 @@
+package cadet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+)
+
+type listenerKey struct{}
+
+type listener struct {
+	server     *http.Server
+	middleware []Middleware
+}
+
+func (s *Server[T]) AddListener(l net.Listener, middleware ...Middleware) {
+	extra := &listener{middleware: middleware}
+
+	extra.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.httpServer.Handler.ServeHTTP(w, r)
+		}),
+		ReadTimeout:       s.httpServer.ReadTimeout,
+		ReadHeaderTimeout: s.httpServer.ReadHeaderTimeout,
+		WriteTimeout:      s.httpServer.WriteTimeout,
+		IdleTimeout:       s.httpServer.IdleTimeout,
+		MaxHeaderBytes:    s.httpServer.MaxHeaderBytes,
+		ErrorLog:          s.httpServer.ErrorLog,
+		Protocols:         s.httpServer.Protocols,
+		ConnContext:       s.httpServer.ConnContext,
+		BaseContext: func(ln net.Listener) context.Context {
+			ctx := context.Background()
+			if s.httpServer.BaseContext != nil {
+				ctx = s.httpServer.BaseContext(ln)
+			}
+
+			return context.WithValue(ctx, listenerKey{}, extra)
+		},
+	}
+
+	s.mu.Lock()
+	s.listeners = append(s.listeners, extra)
+	s.mu.Unlock()
+
+	s.logger.Info("starting listener", "addr", l.Addr().String(), "path", s.path)
+
+	go func() {
+		if err := extra.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("listener failed", "addr", l.Addr().String(), "error", err)
+		}
+	}()
+}
+
+func AllowCommands(patterns ...string) Middleware {
+	return filterCommands(patterns, true)
+}
+
+func DenyCommands(patterns ...string) Middleware {
+	return filterCommands(patterns, false)
+}
+
+func filterCommands(patterns []string, allow bool) Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			name := peekCommandName(r)
+
+			matched := slices.ContainsFunc(patterns, func(pattern string) bool {
+				ok, _ := path.Match(pattern, name)
+				return ok
+			})
+
+			if name != "" && matched != allow {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+}
+
+func (s *Server[T]) withListener() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			extra, ok := r.Context().Value(listenerKey{}).(*listener)
+			if !ok {
+				h(w, r)
+				return
+			}
+
+			handler := h
+			for i := len(extra.middleware) - 1; i >= 0; i-- {
+				handler = extra.middleware[i](handler)
+			}
+
+			handler(w, r)
+		}
+	}
+}
+
+func (s *Server[T]) shutdownListeners(ctx context.Context) error {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	var err error
+
+	for _, extra := range listeners {
+		if shutdownErr := extra.server.Shutdown(ctx); err == nil {
+			err = shutdownErr
+		}
+	}
+
+	return err
+}
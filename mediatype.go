@@ -0,0 +1,36 @@
+package cadet
+
+import "strings"
+
+var defaultCodecTable = defaultCodecs()
+
+func mediaType(value string) (string, bool) {
+	value, _, _ = strings.Cut(value, ";")
+	value = strings.TrimSpace(value)
+
+	kind, subtype, ok := strings.Cut(value, "/")
+	if !ok || !isToken(kind) || !isToken(subtype) {
+		return "", false
+	}
+
+	return strings.ToLower(value), true
+}
+
+func isToken(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package cadet
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Meta struct {
+	Description     string
+	Tags            []string
+	Deprecated      bool
+	DeprecatedSince time.Time
+	Sunset          time.Time
+}
+
+func WithMeta(meta Meta) CommandOption {
+	return func(o *commandOptions) {
+		o.meta = meta
+	}
+}
+
+func (m *Meta) deprecationHeaders(header http.Header) {
+	if m.DeprecatedSince.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		header.Set("Deprecation", "@"+strconv.FormatInt(m.DeprecatedSince.Unix(), 10))
+	}
+
+	if !m.Sunset.IsZero() {
+		header.Set("Sunset", m.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
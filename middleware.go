@@ -0,0 +1,59 @@
+package cadet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			h(w, r)
+
+			info := ResponseInfo(r)
+
+			logger.Info("access",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote", ClientIP(r),
+				"status", info.Status,
+				"size", info.Size,
+				"duration", time.Since(start),
+			)
+		}
+	}
+}
+
+type requestIDKey struct{}
+
+func RequestID() Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if id == "" || len(id) > 128 {
+				id = newRequestID()
+			}
+
+			w.Header().Set("X-Request-ID", id)
+			h(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		}
+	}
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,76 @@
+package cadet
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type MsgPackCodec struct{}
+
+func (MsgPackCodec) Decode(r *http.Request) (*Command, error) {
+	defer r.Body.Close()
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	if _, err := buffer.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	envelope := &struct {
+		Name  string             `msgpack:"name"`
+		Data  msgpack.RawMessage `msgpack:"data"`
+		Async bool               `msgpack:"async"`
+	}{}
+
+	if err := msgpack.Unmarshal(buffer.Bytes(), envelope); err != nil {
+		return nil, err
+	}
+
+	command := newCommand()
+	command.Name = envelope.Name
+	command.Async = envelope.Async
+
+	if len(envelope.Data) > 0 {
+		var data any
+		if err := msgpack.Unmarshal(envelope.Data, &data); err != nil {
+			return nil, err
+		}
+
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+
+		command.Data = encoded
+	}
+
+	return command, nil
+}
+
+func (MsgPackCodec) Encode(w http.ResponseWriter, v any) error {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	encoder := msgpack.NewEncoder(buffer)
+	encoder.SetCustomStructTag("json")
+
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/msgpack")
+	_, err := w.Write(buffer.Bytes())
+
+	return err
+}
+
+func MsgPack(response any) Response {
+	return func(w http.ResponseWriter) {
+		if err := (MsgPackCodec{}).Encode(w, response); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
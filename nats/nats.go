@@ -0,0 +1,126 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinrue/cadet/internal/transport"
+	natslib "github.com/nats-io/nats.go"
+)
+
+type Option func(*subscriber)
+
+type subscriber struct {
+	handler         http.Handler
+	path            string
+	queue           string
+	timeout         time.Duration
+	commandSubjects bool
+	subjectPrefix   string
+}
+
+type envelope struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func WithPath(path string) Option {
+	return func(s *subscriber) {
+		s.path = path
+	}
+}
+
+func WithQueue(queue string) Option {
+	return func(s *subscriber) {
+		s.queue = queue
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *subscriber) {
+		s.timeout = timeout
+	}
+}
+
+func WithCommandSubjects() Option {
+	return func(s *subscriber) {
+		s.commandSubjects = true
+	}
+}
+
+func Subscribe(conn *natslib.Conn, subject string, handler http.Handler, options ...Option) (*natslib.Subscription, error) {
+	s := &subscriber{
+		handler: handler,
+		path:    "/",
+		timeout: 30 * time.Second,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.commandSubjects {
+		s.subjectPrefix = subject + "."
+		subject = s.subjectPrefix + ">"
+	}
+
+	if s.queue != "" {
+		return conn.QueueSubscribe(subject, s.queue, s.serve)
+	}
+
+	return conn.Subscribe(subject, s.serve)
+}
+
+func (s *subscriber) serve(msg *natslib.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	body := msg.Data
+
+	if s.commandSubjects {
+		encoded, err := json.Marshal(&envelope{strings.TrimPrefix(msg.Subject, s.subjectPrefix), body})
+		if err != nil {
+			s.reply(msg, http.StatusUnprocessableEntity, nil, nil)
+			return
+		}
+
+		body = encoded
+	}
+
+	req, err := transport.NewRequest(ctx, s.path, body)
+	if err != nil {
+		s.reply(msg, http.StatusInternalServerError, nil, nil)
+		return
+	}
+
+	for key, values := range msg.Header {
+		if strings.EqualFold(key, "Content-Type") {
+			continue
+		}
+
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	rec := transport.Serve(s.handler, req)
+	s.reply(msg, rec.Status, rec.Header(), rec.Body.Bytes())
+}
+
+func (s *subscriber) reply(msg *natslib.Msg, status int, header http.Header, body []byte) {
+	if msg.Reply == "" {
+		return
+	}
+
+	response := natslib.NewMsg(msg.Reply)
+	response.Data = body
+	response.Header.Set("Cadet-Status", strconv.Itoa(status))
+
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		response.Header.Set("Content-Type", contentType)
+	}
+
+	msg.RespondMsg(response)
+}
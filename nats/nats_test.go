@@ -0,0 +1,102 @@
+package nats_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/martinrue/cadet"
+	cadetnats "github.com/martinrue/cadet/nats"
+	"github.com/nats-io/nats-server/v2/server"
+	natslib "github.com/nats-io/nats.go"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func createConnection(t *testing.T) *natslib.Conn {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	assertNoError(t, err)
+
+	go ns.Start()
+	t.Cleanup(ns.Shutdown)
+
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+
+	conn, err := natslib.Connect(ns.ClientURL())
+	assertNoError(t, err)
+
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func createServer() *cadet.Server[string] {
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Command("greet", func(r *cadet.Request, ctx string) cadet.Response {
+		data := &struct {
+			Name string `json:"name"`
+		}{}
+
+		if err := r.ReadCommand(data); err != nil {
+			return cadet.Status(http.StatusUnprocessableEntity)
+		}
+
+		return cadet.Text("hello " + data.Name + r.RawRequest.Header.Get("X-Suffix"))
+	})
+
+	return server
+}
+
+func TestSubscribe(t *testing.T) {
+	conn := createConnection(t)
+
+	_, err := cadetnats.Subscribe(conn, "cadet", createServer())
+	assertNoError(t, err)
+
+	msg := natslib.NewMsg("cadet")
+	msg.Data = []byte(`{"name":"greet","data":{"name":"cadet"}}`)
+	msg.Header.Set("X-Suffix", "!")
+
+	resp, err := conn.RequestMsg(msg, 5*time.Second)
+	assertNoError(t, err)
+	assertEqual(t, string(resp.Data), "hello cadet!")
+	assertEqual(t, resp.Header.Get("Cadet-Status"), "200")
+	assertEqual(t, resp.Header.Get("Content-Type"), "text/plain; charset=utf-8")
+
+	resp, err = conn.Request("cadet", []byte(`{"name":"missing"}`), 5*time.Second)
+	assertNoError(t, err)
+	assertEqual(t, resp.Header.Get("Cadet-Status"), "404")
+}
+
+func TestSubscribeCommandSubjects(t *testing.T) {
+	conn := createConnection(t)
+
+	_, err := cadetnats.Subscribe(conn, "commands", createServer(), cadetnats.WithCommandSubjects(), cadetnats.WithQueue("workers"))
+	assertNoError(t, err)
+
+	resp, err := conn.Request("commands.greet", []byte(`{"name":"nats"}`), 5*time.Second)
+	assertNoError(t, err)
+	assertEqual(t, string(resp.Data), "hello nats")
+
+	resp, err = conn.Request("commands.greet", []byte(`[]`), 5*time.Second)
+	assertNoError(t, err)
+	assertEqual(t, resp.Header.Get("Cadet-Status"), "422")
+}
@@ -0,0 +1,76 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	ndjsonFlushSize     = 32 * 1024
+	ndjsonFlushInterval = 100 * time.Millisecond
+)
+
+type JSONStream struct {
+	w       http.ResponseWriter
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+	flushed time.Time
+	sent    bool
+}
+
+func (s *JSONStream) Encode(v any) error {
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+
+	if s.buffer.Len() >= ndjsonFlushSize || time.Since(s.flushed) >= ndjsonFlushInterval {
+		return s.Flush()
+	}
+
+	return nil
+}
+
+func (s *JSONStream) Flush() error {
+	if !s.sent {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.sent = true
+	}
+
+	s.flushed = time.Now()
+
+	if _, err := s.w.Write(s.buffer.Bytes()); err != nil {
+		return err
+	}
+
+	s.buffer.Reset()
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+func NDJSON(write func(stream *JSONStream) error) Response {
+	return func(w http.ResponseWriter) {
+		stream := &JSONStream{w: w, flushed: time.Now()}
+		stream.encoder = json.NewEncoder(&stream.buffer)
+
+		if err := write(stream); err != nil {
+			if !stream.sent {
+				Fail(err)(w)
+				return
+			}
+
+			if rw, ok := w.(*responseWriter); ok && rw.fail != nil {
+				rw.fail(err)
+			}
+
+			return
+		}
+
+		stream.Flush()
+	}
+}
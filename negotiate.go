@@ -0,0 +1,94 @@
+package cadet
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type codecsKey struct{}
+
+type acceptedType struct {
+	mediaType string
+	quality   float64
+}
+
+func Body(response any) Response {
+	return func(w http.ResponseWriter) {
+		w.Header().Add("Vary", "Accept")
+
+		codec := negotiate(requestFrom(w))
+		if codec == nil {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+
+		if err := codec.Encode(w, response); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
+
+func negotiate(r *http.Request) Codec {
+	lookup, ok := r.Context().Value(codecsKey{}).(func(string) Codec)
+	if !ok {
+		lookup = func(mediaType string) Codec {
+			return defaultCodecTable[mediaType]
+		}
+	}
+
+	accepted := parseAccept(r.Header.Get("Accept"))
+	if len(accepted) == 0 {
+		return JSONCodec{}
+	}
+
+	for _, accept := range accepted {
+		switch accept.mediaType {
+		case "*/*", "application/*":
+			return JSONCodec{}
+		case "multipart/form-data", "application/x-www-form-urlencoded":
+			continue
+		}
+
+		if codec := lookup(accept.mediaType); codec != nil {
+			return codec
+		}
+	}
+
+	return nil
+}
+
+func parseAccept(header string) []acceptedType {
+	accepted := []acceptedType{}
+
+	for part := range strings.SplitSeq(header, ",") {
+		value, ok := mediaType(part)
+		if !ok {
+			continue
+		}
+
+		_, params, _ := strings.Cut(part, ";")
+
+		quality := 1.0
+
+		for param := range strings.SplitSeq(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if quality > 0 {
+			accepted = append(accepted, acceptedType{value, quality})
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+
+	return accepted
+}
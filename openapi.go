@@ -0,0 +1,146 @@
+package cadet
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+func (s *Server[T]) OpenAPI() map[string]any {
+	names, commands := s.sortedCommands()
+
+	generator := newSchemaGenerator("#/components/schemas/")
+
+	errorSchema := generator.define("Error", map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+		"required":   []string{"error"},
+	})
+
+	requests := []any{}
+	responses := []any{}
+	mapping := make(map[string]any)
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		options := commands[i].options
+
+		data := map[string]any{}
+		if options.input != nil {
+			data = generator.schemaOf(options.input)
+		}
+
+		request := map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name": map[string]any{"type": "string", "enum": []string{name}},
+				"data": data,
+			},
+			"required": []string{"name"},
+		}
+
+		if options.meta.Description != "" {
+			request["description"] = options.meta.Description
+		}
+
+		if len(options.meta.Tags) > 0 {
+			request["x-tags"] = options.meta.Tags
+		}
+
+		if options.meta.Deprecated {
+			request["deprecated"] = true
+		}
+
+		ref := "#/components/schemas/" + generator.define(schemaName(name), request)
+		requests = append(requests, map[string]any{"$ref": ref})
+		mapping[name] = ref
+
+		if options.output != nil {
+			responses = append(responses, generator.schemaOf(options.output))
+		}
+	}
+
+	status := func(code int) map[string]any {
+		return map[string]any{"description": http.StatusText(code)}
+	}
+
+	success := map[string]any{"description": "Command executed"}
+	if len(responses) > 0 {
+		success["content"] = map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"oneOf": responses}},
+		}
+	}
+
+	operations := map[string]any{}
+
+	for _, method := range s.methods {
+		operation := map[string]any{
+			"operationId": "command",
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{
+							"oneOf":         requests,
+							"discriminator": map[string]any{"propertyName": "name", "mapping": mapping},
+						},
+					},
+					"multipart/form-data": map[string]any{
+						"schema": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"command": map[string]any{"type": "string"},
+							},
+							"required": []string{"command"},
+						},
+					},
+				},
+			},
+			"responses": map[string]any{
+				"200": success,
+				"404": status(http.StatusNotFound),
+				"405": status(http.StatusMethodNotAllowed),
+				"415": status(http.StatusUnsupportedMediaType),
+				"422": status(http.StatusUnprocessableEntity),
+				"default": map[string]any{
+					"description": "Command failed",
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": map[string]any{"$ref": "#/components/schemas/" + errorSchema},
+						},
+					},
+				},
+			},
+		}
+
+		if method != http.MethodPost {
+			operation["operationId"] = "command" + method[:1] + strings.ToLower(method[1:])
+		}
+
+		operations[strings.ToLower(method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "cadet",
+			"version": "1.0.0",
+		},
+		"paths": map[string]any{
+			s.path: operations,
+		},
+		"components": map[string]any{
+			"schemas": generator.definitions,
+		},
+	}
+}
+
+func schemaName(command string) string {
+	name := []rune(functionName(command))
+	name[0] = unicode.ToUpper(name[0])
+
+	return string(name) + "Command"
+}
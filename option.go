@@ -0,0 +1,48 @@
+package cadet
+
+import (
+	"reflect"
+	"time"
+)
+
+type CommandOption func(*commandOptions)
+
+type commandOptions struct {
+	input       reflect.Type
+	output      reflect.Type
+	breaker     *breaker
+	timeout     time.Duration
+	cache       time.Duration
+	cacheKey    func(r *Request) string
+	strict      bool
+	permissions []string
+	scopes      []string
+	meta        Meta
+}
+
+func WithTypes(input any, output any) CommandOption {
+	return func(o *commandOptions) {
+		o.input = reflect.TypeOf(input)
+		o.output = reflect.TypeOf(output)
+	}
+}
+
+func WithStrictDecode() CommandOption {
+	return func(o *commandOptions) {
+		o.strict = true
+	}
+}
+
+func (o *commandOptions) info(name string) CommandInfo {
+	return CommandInfo{Name: name, Permissions: o.permissions, Scopes: o.scopes, Meta: o.meta}
+}
+
+func newCommandOptions(options []CommandOption) commandOptions {
+	result := commandOptions{}
+
+	for _, option := range options {
+		option(&result)
+	}
+
+	return result
+}
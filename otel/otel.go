@@ -0,0 +1,51 @@
+package otel
+
+import (
+	"net/http"
+
+	"github.com/martinrue/cadet"
+	"github.com/martinrue/cadet/internal/observe"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/martinrue/cadet"
+
+type observer struct {
+	span trace.Span
+}
+
+func (o *observer) Command(name string) {
+	o.span.SetName(name)
+	o.span.SetAttributes(attribute.String("cadet.command", name))
+}
+
+func (o *observer) Error(err error) {
+	o.span.RecordError(err)
+}
+
+func Tracing(provider trace.TracerProvider) cadet.Middleware {
+	tracer := provider.Tracer(tracerName)
+	propagator := propagation.TraceContext{}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := tracer.Start(ctx, r.URL.Path, trace.WithSpanKind(trace.SpanKindServer))
+			defer span.End()
+
+			r = r.WithContext(observe.With(ctx, &observer{span}))
+			h(w, r)
+
+			status := cadet.ResponseInfo(r).Status
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package otel_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martinrue/cadet"
+	cadetotel "github.com/martinrue/cadet/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func assertEqual(t *testing.T, value any, expected any) {
+	t.Helper()
+
+	if value != expected {
+		t.Fatalf("Expected %v, got %v", expected, value)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	server := cadet.NewServer(&cadet.Config{}, "")
+	server.Use(cadetotel.Tracing(provider))
+	server.Command("echo", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Status(http.StatusOK)
+	})
+
+	server.Command("fail", func(r *cadet.Request, ctx string) cadet.Response {
+		return cadet.Error(http.StatusInternalServerError, "oops")
+	})
+
+	request := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	request(`{"name":"echo"}`)
+	request(`{"name":"fail"}`)
+	request(`invalid`)
+
+	ended := spans.Ended()
+	assertEqual(t, len(ended), 3)
+
+	assertEqual(t, ended[0].Name(), "echo")
+	assertEqual(t, ended[0].SpanKind(), trace.SpanKindServer)
+	assertEqual(t, ended[0].SpanContext().TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assertEqual(t, ended[0].Parent().SpanID().String(), "00f067aa0ba902b7")
+	assertEqual(t, ended[0].Status().Code, codes.Unset)
+
+	assertEqual(t, ended[1].Name(), "fail")
+	assertEqual(t, ended[1].Status().Code, codes.Error)
+
+	assertEqual(t, len(ended[2].Events()), 1)
+	assertEqual(t, ended[2].Events()[0].Name, "exception")
+}
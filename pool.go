@@ -0,0 +1,18 @@
+package cadet
+
+import "sync"
+
+var commandPool = sync.Pool{
+	New: func() any {
+		return &Command{}
+	},
+}
+
+func newCommand() *Command {
+	return commandPool.Get().(*Command)
+}
+
+func releaseCommand(command *Command) {
+	*command = Command{}
+	commandPool.Put(command)
+}
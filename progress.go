@@ -0,0 +1,110 @@
+package cadet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type progressKey struct{}
+
+type progressEvent struct {
+	Percent int    `json:"percent"`
+	Message string `json:"message,omitempty"`
+}
+
+type eventStream struct {
+	http.ResponseWriter
+	started bool
+	status  int
+	header  http.Header
+	body    bytes.Buffer
+}
+
+func (c *Request) Progress(percent int, message string) {
+	if c.progress != nil {
+		c.progress(percent, message)
+	}
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func (w *eventStream) Header() http.Header {
+	if !w.started {
+		return w.ResponseWriter.Header()
+	}
+
+	return w.header
+}
+
+func (w *eventStream) WriteHeader(status int) {
+	if !w.started {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *eventStream) Write(data []byte) (int, error) {
+	if !w.started {
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *eventStream) Flush() {
+	if !w.started {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+func (w *eventStream) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *eventStream) progress(percent int, message string) {
+	if !w.started {
+		w.started = true
+		w.header = w.ResponseWriter.Header().Clone()
+
+		w.ResponseWriter.Header().Set("Content-Type", "text/event-stream")
+		w.ResponseWriter.Header().Set("Cache-Control", "no-cache")
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+
+	data, _ := json.Marshal(&progressEvent{percent, message})
+	w.event("progress", data)
+}
+
+func (w *eventStream) finish() {
+	if !w.started {
+		return
+	}
+
+	event := "result"
+	if w.status >= http.StatusBadRequest {
+		event = "error"
+	}
+
+	w.event(event, w.body.Bytes())
+}
+
+func (w *eventStream) event(name string, data []byte) {
+	fmt.Fprintf(w.ResponseWriter, "event: %s\n", name)
+
+	for line := range strings.SplitSeq(strings.TrimSuffix(string(data), "\n"), "\n") {
+		fmt.Fprintf(w.ResponseWriter, "data: %s\n", line)
+	}
+
+	fmt.Fprint(w.ResponseWriter, "\n")
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
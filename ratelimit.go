@@ -0,0 +1,120 @@
+package cadet
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimitOptions struct {
+	Limit    Limit
+	Commands map[string]Limit
+	Key      func(r *http.Request) string
+}
+
+type bucket struct {
+	tokens  float64
+	burst   float64
+	rate    float64
+	updated time.Time
+}
+
+type rateLimiter struct {
+	options RateLimitOptions
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func RateLimit(options RateLimitOptions) Middleware {
+	if options.Key == nil {
+		options.Key = ClientIP
+	}
+
+	limiter := &rateLimiter{
+		options: options,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := options.Key(r)
+			limit := options.Limit
+
+			if name := peekCommandName(r); name != "" {
+				if override, ok := options.Commands[name]; ok {
+					key += "\x00" + name
+					limit = override
+				}
+			}
+
+			if wait := limiter.take(key, limit); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			h(w, r)
+		}
+	}
+}
+
+func (l *rateLimiter) take(key string, limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	burst := math.Max(float64(limit.Burst), 1)
+
+	if now.Sub(l.swept) > time.Minute {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, burst: burst, rate: limit.Rate, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return 0
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.rate >= b.burst {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.swept = now
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,156 @@
+package cadet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+type Recording struct {
+	Time   time.Time       `json:"time"`
+	Name   string          `json:"name"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Status int             `json:"status"`
+}
+
+type RecordStore interface {
+	Save(ctx context.Context, recording *Recording) error
+}
+
+type RecordOptions struct {
+	Store    RecordStore
+	Redact   []string
+	Commands []string
+}
+
+type FileRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileRecorder(path string) (*FileRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileRecorder{file: file}, nil
+}
+
+func (f *FileRecorder) Save(ctx context.Context, recording *Recording) error {
+	line, err := json.Marshal(recording)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *FileRecorder) Close() error {
+	return f.file.Close()
+}
+
+func Record(options RecordOptions) Middleware {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			command := peekCommand(r)
+			if command == nil || len(options.Commands) > 0 && !slices.Contains(options.Commands, command.Name) {
+				h(w, r)
+				return
+			}
+
+			recording := &Recording{
+				Time: time.Now().UTC(),
+				Name: command.Name,
+				Data: redact(command.Data, options.Redact),
+			}
+
+			recorder := &recordingWriter{ResponseWriter: w}
+			h(recorder, r)
+
+			recording.Status = recorder.Status()
+			options.Store.Save(context.WithoutCancel(r.Context()), recording)
+		}
+	}
+}
+
+func ReadRecordings(reader io.Reader) ([]*Recording, error) {
+	recordings := []*Recording{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		recording := &Recording{}
+		if err := json.Unmarshal(line, recording); err != nil {
+			return nil, err
+		}
+
+		recordings = append(recordings, recording)
+	}
+
+	return recordings, scanner.Err()
+}
+
+func (s *Server[T]) Replay(ctx context.Context, recording *Recording) (Result, error) {
+	return s.Invoke(ctx, recording.Name, recording.Data)
+}
+
+func redact(data json.RawMessage, fields []string) json.RawMessage {
+	if len(data) == 0 || len(fields) == 0 {
+		return data
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+
+	result, err := json.Marshal(redactValue(value, fields))
+	if err != nil {
+		return nil
+	}
+
+	return result
+}
+
+func redactValue(value any, fields []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if slices.ContainsFunc(fields, func(name string) bool { return strings.EqualFold(name, key) }) {
+				v[key] = redacted
+				continue
+			}
+
+			v[key] = redactValue(field, fields)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item, fields)
+		}
+	}
+
+	return value
+}
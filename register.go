@@ -0,0 +1,80 @@
+package cadet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+var (
+	requestType = reflect.TypeOf((*Request)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func (s *Server[T]) Register(service any, options ...CommandOption) error {
+	value := reflect.ValueOf(service)
+	if !value.IsValid() {
+		return errors.New("service must not be nil")
+	}
+
+	registered := 0
+
+	for i := range value.NumMethod() {
+		method := value.Type().Method(i)
+
+		handler, input, output, ok := serviceMethod[T](value.Method(i))
+		if !ok {
+			continue
+		}
+
+		commandOptions := append([]CommandOption{WithTypes(input, output)}, options...)
+		s.register(inferCommandName(method.Name), handler, nil, commandOptions)
+		registered++
+	}
+
+	if registered == 0 {
+		return fmt.Errorf("%T has no methods of the form func(*cadet.Request, In) (Out, error)", service)
+	}
+
+	return nil
+}
+
+func serviceMethod[T any](method reflect.Value) (func(*Request, T) Response, any, any, bool) {
+	t := method.Type()
+
+	if t.NumIn() != 2 || t.In(0) != requestType || t.NumOut() != 2 || t.Out(1) != errorType {
+		return nil, nil, nil, false
+	}
+
+	in := t.In(1)
+	input := reflect.Zero(in).Interface()
+	output := reflect.Zero(t.Out(0)).Interface()
+
+	handler := func(r *Request, context T) Response {
+		data := r.command.Data
+		if len(data) == 0 {
+			data = json.RawMessage("null")
+		}
+
+		arg := reflect.New(derefType(in))
+		if err := decodeData(data, arg.Interface(), r.decoding); err != nil {
+			return Error(http.StatusBadRequest, err.Error())
+		}
+
+		if in.Kind() != reflect.Pointer {
+			arg = arg.Elem()
+		}
+
+		results := method.Call([]reflect.Value{reflect.ValueOf(r), arg})
+
+		if err, _ := results[1].Interface().(error); err != nil {
+			return Fail(err)
+		}
+
+		return JSON(results[0].Interface())
+	}
+
+	return handler, input, output, true
+}
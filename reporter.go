@@ -0,0 +1,37 @@
+package cadet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, meta ErrorMeta)
+}
+
+type ErrorMeta struct {
+	Command   string
+	RequestID string
+	Status    int
+}
+
+func (s *Server[T]) reportFailure(r *http.Request, name string, w *recordingWriter, err error) {
+	var panicked *PanicError
+
+	status := w.Status()
+	if status < http.StatusInternalServerError && !errors.As(err, &panicked) {
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("command responded with %d %s", status, http.StatusText(status))
+	}
+
+	s.reporter.Report(r.Context(), err, ErrorMeta{
+		Command:   name,
+		RequestID: requestIDFrom(r.Context()),
+		Status:    status,
+	})
+}
@@ -1,14 +1,33 @@
 package cadet
 
 import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 type Request struct {
-	command     *Command
+	command     Command
 	RawResponse http.ResponseWriter
 	RawRequest  *http.Request
+	progress    func(percent int, message string)
+	validator   Validator
+	decoding    decodeOptions
+	tenant      string
+	finished    atomic.Bool
+}
+
+type decodeOptions struct {
+	strict    bool
+	useNumber bool
 }
 
 func (c *Request) GetCommandName() string {
@@ -16,5 +35,78 @@ func (c *Request) GetCommandName() string {
 }
 
 func (c *Request) ReadCommand(obj any) error {
-	return json.Unmarshal(c.command.Data, obj)
+	if err := decodeData(c.command.Data, obj, c.decoding); err != nil {
+		return err
+	}
+
+	if c.validator == nil {
+		return tagValidator{}.Validate(obj)
+	}
+
+	return c.validator.Validate(obj)
+}
+
+func (c *Request) ReadProto(msg proto.Message) error {
+	encoded := ""
+	if err := json.Unmarshal(c.command.Data, &encoded); err != nil {
+		return protojson.Unmarshal(c.command.Data, msg)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+func (c *Request) ClientCertificate() *x509.Certificate {
+	tls := c.RawRequest.TLS
+	if tls == nil || len(tls.VerifiedChains) == 0 || len(tls.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return tls.VerifiedChains[0][0]
+}
+
+func (c *Request) Context() context.Context {
+	return c.RawRequest.Context()
+}
+
+func (c *Request) RequestID() string {
+	return requestIDFrom(c.RawRequest.Context())
+}
+
+func (c *Request) Tenant() string {
+	return c.tenant
+}
+
+func (c *Request) Principal() Principal {
+	return principalFrom(c.RawRequest.Context())
+}
+
+func decodeData(data json.RawMessage, obj any, options decodeOptions) error {
+	if !options.strict && !options.useNumber {
+		return json.Unmarshal(data, obj)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	if options.strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if options.useNumber {
+		decoder.UseNumber()
+	}
+
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("unexpected data after command data")
+	}
+
+	return nil
 }
@@ -1,16 +1,67 @@
 package cadet
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type Response func(w http.ResponseWriter)
 
+func (r Response) WithStatus(status int) Response {
+	return r.with(status, nil)
+}
+
+func (r Response) WithHeader(name string, value string) Response {
+	return r.with(0, func(header http.Header) {
+		header.Set(name, value)
+	})
+}
+
+func (r Response) WithCookie(cookie *http.Cookie) Response {
+	return r.with(0, func(header http.Header) {
+		if v := cookie.String(); v != "" {
+			header.Add("Set-Cookie", v)
+		}
+	})
+}
+
+func (r Response) WithCache(maxAge time.Duration) Response {
+	return r.WithHeader("Cache-Control", "max-age="+strconv.Itoa(int(maxAge.Seconds())))
+}
+
+func (r Response) WithNoStore() Response {
+	return r.WithHeader("Cache-Control", "no-store")
+}
+
+func (r Response) with(status int, apply func(http.Header)) Response {
+	return func(w http.ResponseWriter) {
+		writer := &builderWriter{status: status, apply: apply}
+
+		if rw, ok := w.(*responseWriter); ok {
+			writer.ResponseWriter = rw.ResponseWriter
+			r(&responseWriter{writer, rw.request, rw.fail})
+		} else {
+			writer.ResponseWriter = w
+			r(writer)
+		}
+
+		writer.finish()
+	}
+}
+
 func JSON(response any) Response {
 	return func(w http.ResponseWriter) {
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		json.NewEncoder(w).Encode(response)
+		JSONCodec{}.Encode(w, response)
 	}
 }
 
@@ -21,12 +72,64 @@ func Text(text string) Response {
 	}
 }
 
+func HTML(html string) Response {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(html))
+	}
+}
+
+func Template(tmpl *template.Template, name string, data any) Response {
+	return func(w http.ResponseWriter) {
+		buffer := getBuffer()
+		defer putBuffer(buffer)
+
+		if err := tmpl.ExecuteTemplate(buffer, name, data); err != nil {
+			Fail(fmt.Errorf("executing template %q: %w", name, err))(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buffer.Bytes())
+	}
+}
+
 func Status(status int) Response {
 	return func(w http.ResponseWriter) {
 		w.WriteHeader(status)
 	}
 }
 
+func NoContent() Response {
+	return Status(http.StatusNoContent)
+}
+
+func Created(response any, location string) Response {
+	return func(w http.ResponseWriter) {
+		if location != "" {
+			w.Header().Set("Location", location)
+		}
+
+		withStatus(http.StatusCreated, response)(w)
+	}
+}
+
+func Accepted(response any) Response {
+	return withStatus(http.StatusAccepted, response)
+}
+
+func withStatus(status int, response any) Response {
+	if response == nil {
+		return Status(status)
+	}
+
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		JSON(response)(w)
+	}
+}
+
 func Error(status int, message string) Response {
 	return func(w http.ResponseWriter) {
 		type response struct {
@@ -38,3 +141,109 @@ func Error(status int, message string) Response {
 		JSON(&response{message})(w)
 	}
 }
+
+func Blob(contentType string, data []byte) Response {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}
+}
+
+func Stream(contentType string, reader io.Reader) Response {
+	return StreamSize(contentType, reader, -1)
+}
+
+func StreamSize(contentType string, reader io.Reader, size int64) Response {
+	return func(w http.ResponseWriter) {
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		w.Header().Set("Content-Type", contentType)
+
+		if size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		}
+
+		flusher, _ := w.(http.Flusher)
+		buffer := make([]byte, 32*1024)
+
+		for {
+			n, err := reader.Read(buffer)
+
+			if n > 0 {
+				if _, err := w.Write(buffer[:n]); err != nil {
+					return
+				}
+
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func File(path string) Response {
+	return func(w http.ResponseWriter) {
+		file, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		name := filepath.Base(path)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+		http.ServeContent(w, requestFrom(w), name, info.ModTime(), file)
+	}
+}
+
+func Attachment(name string, reader io.Reader) Response {
+	return func(w http.ResponseWriter) {
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+
+		if seeker, ok := reader.(io.ReadSeeker); ok {
+			http.ServeContent(w, requestFrom(w), name, time.Time{}, seeker)
+			return
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+
+		if contentType == "" {
+			buffer := make([]byte, 512)
+			n, err := io.ReadFull(reader, buffer)
+			if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			contentType = http.DetectContentType(buffer[:n])
+			reader = io.MultiReader(bytes.NewReader(buffer[:n]), reader)
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		io.Copy(w, reader)
+	}
+}
@@ -0,0 +1,30 @@
+package cadet
+
+import (
+	"context"
+	"net/http"
+)
+
+type responseInfoKey struct{}
+
+type ResponseDetails struct {
+	Status  int
+	Size    int64
+	Written bool
+}
+
+func ResponseInfo(r *http.Request) ResponseDetails {
+	recorder, ok := r.Context().Value(responseInfoKey{}).(*recordingWriter)
+	if !ok {
+		return ResponseDetails{}
+	}
+
+	return ResponseDetails{Status: recorder.Status(), Size: recorder.size, Written: recorder.status != 0}
+}
+
+func withResponseInfo(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &recordingWriter{ResponseWriter: w}
+		h(recorder, r.WithContext(context.WithValue(r.Context(), responseInfoKey{}, recorder)))
+	}
+}
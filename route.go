@@ -0,0 +1,59 @@
+package cadet
+
+import "time"
+
+type Route[T any] struct {
+	server  *Server[T]
+	group   *Group[T]
+	name    string
+	options []CommandOption
+}
+
+func (s *Server[T]) Route(name string) *Route[T] {
+	return &Route[T]{server: s, name: name}
+}
+
+func (g *Group[T]) Route(name string) *Route[T] {
+	return &Route[T]{server: g.server, group: g, name: g.commandName(name)}
+}
+
+func (r *Route[T]) Types(input any, output any) *Route[T] {
+	return r.With(WithTypes(input, output))
+}
+
+func (r *Route[T]) StrictDecode() *Route[T] {
+	return r.With(WithStrictDecode())
+}
+
+func (r *Route[T]) Timeout(timeout time.Duration) *Route[T] {
+	return r.With(WithTimeout(timeout))
+}
+
+func (r *Route[T]) Cache(ttl time.Duration) *Route[T] {
+	return r.With(WithCache(ttl))
+}
+
+func (r *Route[T]) CircuitBreaker(options BreakerOptions) *Route[T] {
+	return r.With(WithCircuitBreaker(options))
+}
+
+func (r *Route[T]) Require(permissions ...string) *Route[T] {
+	return r.With(RequirePermission(permissions...))
+}
+
+func (r *Route[T]) RequireScope(scopes ...string) *Route[T] {
+	return r.With(RequireScope(scopes...))
+}
+
+func (r *Route[T]) Meta(meta Meta) *Route[T] {
+	return r.With(WithMeta(meta))
+}
+
+func (r *Route[T]) With(options ...CommandOption) *Route[T] {
+	r.options = append(r.options, options...)
+	return r
+}
+
+func (r *Route[T]) Handle(handler func(r *Request, context T) Response) {
+	r.server.register(r.name, handler, r.group, r.options)
+}
@@ -0,0 +1,47 @@
+package cadet
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func newScheduler() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+func (s *Server[T]) Schedule(spec string, name string, data any) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
+
+	go s.runSchedule(schedule, name, data)
+
+	return nil
+}
+
+func (s *Server[T]) runSchedule(schedule cron.Schedule, name string, data any) {
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+
+		select {
+		case <-s.scheduler.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		result, err := s.Invoke(s.scheduler, name, data)
+		if err != nil {
+			s.logger.Error("scheduled command failed", "command", name, "error", err)
+			continue
+		}
+
+		if result.Status >= http.StatusInternalServerError {
+			s.logger.Error("scheduled command failed", "command", name, "status", result.Status)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cadet
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type schemaGenerator struct {
+	prefix      string
+	definitions map[string]any
+	names       map[reflect.Type]string
+	used        map[string]bool
+}
+
+func newSchemaGenerator(prefix string) *schemaGenerator {
+	return &schemaGenerator{
+		prefix:      prefix,
+		definitions: make(map[string]any),
+		names:       make(map[reflect.Type]string),
+		used:        make(map[string]bool),
+	}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) map[string]any {
+	t = derefType(t)
+
+	switch {
+	case t == nil || t == rawMessageType:
+		return map[string]any{}
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+
+		return map[string]any{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		return g.ref(t)
+	}
+
+	return map[string]any{}
+}
+
+func (g *schemaGenerator) ref(t reflect.Type) map[string]any {
+	name, ok := g.names[t]
+
+	if !ok {
+		name = g.define(typeName(t), nil)
+		g.names[t] = name
+		g.definitions[name] = g.object(t)
+	}
+
+	return map[string]any{"$ref": g.prefix + name}
+}
+
+func (g *schemaGenerator) define(name string, schema map[string]any) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	g.used[unique] = true
+
+	if schema != nil {
+		g.definitions[unique] = schema
+	}
+
+	return unique
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+
+	for _, field := range jsonFields(t) {
+		properties[field.name] = g.schemaOf(field.typ)
+
+		if !field.optional {
+			required = append(required, field.name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
@@ -0,0 +1,52 @@
+package cadet
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SecureHeadersOptions struct {
+	HSTSMaxAge            time.Duration
+	DisableHSTS           bool
+	FrameOptions          string
+	ContentSecurityPolicy string
+}
+
+func SecureHeaders(options ...SecureHeadersOptions) Middleware {
+	config := SecureHeadersOptions{}
+	if len(options) > 0 {
+		config = options[0]
+	}
+
+	if config.HSTSMaxAge == 0 {
+		config.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+
+	if config.FrameOptions == "" {
+		config.FrameOptions = "DENY"
+	}
+
+	if config.ContentSecurityPolicy == "" {
+		config.ContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+	}
+
+	hsts := "max-age=" + strconv.Itoa(int(config.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+
+			if !config.DisableHSTS {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", config.FrameOptions)
+			header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+			header.Set("Referrer-Policy", "no-referrer")
+
+			h(w, r)
+		}
+	}
+}
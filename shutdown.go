@@ -0,0 +1,59 @@
+package cadet
+
+import "sync"
+
+type tracker struct {
+	mu       sync.Mutex
+	active   int
+	stopping bool
+	idle     chan struct{}
+}
+
+func newTracker() *tracker {
+	return &tracker{idle: make(chan struct{})}
+}
+
+func (t *tracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopping {
+		return false
+	}
+
+	t.active++
+	return true
+}
+
+func (t *tracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+
+	if t.stopping && t.active == 0 {
+		close(t.idle)
+	}
+}
+
+func (t *tracker) stop() (int, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.stopping {
+		t.stopping = true
+
+		if t.active == 0 {
+			close(t.idle)
+		}
+	}
+
+	return t.active, t.idle
+}
+
+func (t *tracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active
+}
@@ -0,0 +1,90 @@
+package cadet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+type tenantKey struct{}
+
+type tenantValue[T any] struct {
+	name    string
+	context T
+}
+
+func TenantContext[T any](r *http.Request) (string, T, bool) {
+	tenant, ok := r.Context().Value(tenantKey{}).(tenantValue[T])
+	return tenant.name, tenant.context, ok
+}
+
+func (s *Server[T]) Tenant(name string, context T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tenants == nil {
+		s.tenants = make(map[string]T)
+	}
+
+	s.tenants[name] = context
+}
+
+func (s *Server[T]) RemoveTenant(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[name]; !ok {
+		return false
+	}
+
+	delete(s.tenants, name)
+	return true
+}
+
+func (s *Server[T]) resolveTenant(r *http.Request) (string, T, error) {
+	if s.tenantResolver == nil {
+		return "", s.context, nil
+	}
+
+	name, err := s.tenantResolver(r)
+	if err != nil {
+		return "", s.context, fmt.Errorf("%w: %w", ErrInvalidTenant, err)
+	}
+
+	if name == "" {
+		return "", s.context, nil
+	}
+
+	s.mu.RLock()
+	tenant, ok := s.tenants[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", s.context, fmt.Errorf("%w: %q is not registered", ErrInvalidTenant, name)
+	}
+
+	return name, tenant, nil
+}
+
+func (s *Server[T]) resolveContext(request *Request) (T, error) {
+	name, tenant, err := s.resolveTenant(request.RawRequest)
+	if err != nil {
+		return tenant, err
+	}
+
+	request.tenant = name
+
+	if s.factory == nil {
+		return tenant, nil
+	}
+
+	r := request.RawRequest
+	if name != "" {
+		r = r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenantValue[T]{name, tenant}))
+	}
+
+	return s.factory(r)
+}
@@ -0,0 +1,104 @@
+package cadet
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type bufferedWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	timedOut bool
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status == 0 && !w.timedOut {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) flushTo(target http.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, values := range w.header {
+		target.Header()[key] = values
+	}
+
+	if w.status != 0 {
+		target.WriteHeader(w.status)
+	}
+
+	target.Write(w.body.Bytes())
+}
+
+func (w *bufferedWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.timedOut = true
+}
+
+func withTimeout(timeout time.Duration, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		buffer := &bufferedWriter{header: w.Header().Clone()}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					panicked <- recovered
+				}
+			}()
+
+			h(buffer, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case recovered := <-panicked:
+			panic(recovered)
+		case <-done:
+			buffer.flushTo(w)
+		case <-ctx.Done():
+			buffer.timeout()
+
+			if ctx.Err() == context.DeadlineExceeded {
+				w.WriteHeader(http.StatusGatewayTimeout)
+			}
+		}
+	}
+}
+
+func WithTimeout(timeout time.Duration) CommandOption {
+	return func(o *commandOptions) {
+		o.timeout = timeout
+	}
+}
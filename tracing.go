@@ -0,0 +1,15 @@
+package cadet
+
+import (
+	"net/http"
+
+	"github.com/martinrue/cadet/internal/observe"
+)
+
+func traceCommand(r *http.Request, name string) {
+	observe.Command(r.Context(), name)
+}
+
+func traceError(r *http.Request, err error) {
+	observe.Error(r.Context(), err)
+}
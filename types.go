@@ -0,0 +1,74 @@
+package cadet
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	invalidName    = regexp.MustCompile("[^A-Za-z0-9_]")
+)
+
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+	index    []int
+	validate string
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func typeName(t reflect.Type) string {
+	return invalidName.ReplaceAllString(t.Name(), "")
+}
+
+func jsonFields(t reflect.Type) []jsonField {
+	fields := []jsonField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			if embedded := derefType(field.Type); embedded.Kind() == reflect.Struct {
+				for _, embeddedField := range jsonFields(embedded) {
+					embeddedField.index = append([]int{i}, embeddedField.index...)
+					fields = append(fields, embeddedField)
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		optional := strings.Contains(options, "omitempty") || field.Type.Kind() == reflect.Pointer
+		fields = append(fields, jsonField{name, field.Type, optional, []int{i}, field.Tag.Get("validate")})
+	}
+
+	return fields
+}
@@ -0,0 +1,218 @@
+package cadet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+const typeScriptClient = `export class CadetError extends Error {
+  constructor(public status: number, message: string) {
+    super(message);
+  }
+}
+
+async function call(url: string, init: RequestInit, name: string, data?: unknown): Promise<any> {
+  const headers = new Headers(init.headers);
+  headers.set("Content-Type", "application/json");
+
+  const response = await fetch(url, {
+    ...init,
+    method: "POST",
+    headers,
+    body: JSON.stringify({ name, data }),
+  });
+
+  const text = await response.text();
+  const isJSON = (response.headers.get("Content-Type") ?? "").startsWith("application/json");
+  const body = isJSON && text !== "" ? JSON.parse(text) : text;
+
+  if (!response.ok) {
+    throw new CadetError(response.status, body?.error ?? response.statusText);
+  }
+
+  return body;
+}
+`
+
+type typeScriptGenerator struct {
+	names  map[reflect.Type]string
+	used   map[string]bool
+	output *bytes.Buffer
+}
+
+func (s *Server[T]) ExportTypeScript(w io.Writer) error {
+	names, commands := s.sortedCommands()
+
+	generator := &typeScriptGenerator{
+		names:  make(map[reflect.Type]string),
+		used:   make(map[string]bool),
+		output: &bytes.Buffer{},
+	}
+
+	functions := &bytes.Buffer{}
+
+	for i, name := range names {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		options := commands[i].options
+
+		input := "data?: unknown"
+		if options.input != nil {
+			input = "data: " + generator.typeOf(options.input)
+		}
+
+		output := "unknown"
+		if options.output != nil {
+			output = generator.typeOf(options.output)
+		}
+
+		if comment := docComment(options.meta); comment != "" {
+			fmt.Fprintf(functions, "    /** %s */\n", comment)
+		}
+
+		fmt.Fprintf(functions, "    %s: (%s): Promise<%s> => call(url, init, %q, data),\n", functionName(name), input, output, name)
+	}
+
+	output := &bytes.Buffer{}
+	fmt.Fprintf(output, "// Code generated by cadet. DO NOT EDIT.\n\n")
+	fmt.Fprintf(output, "%s", generator.output.String())
+	fmt.Fprintf(output, "%s\n", typeScriptClient)
+	fmt.Fprintf(output, "export function createClient(url: string, init: RequestInit = {}) {\n  return {\n%s  };\n}\n", functions.String())
+
+	_, err := output.WriteTo(w)
+	return err
+}
+
+func (g *typeScriptGenerator) typeOf(t reflect.Type) string {
+	t = derefType(t)
+
+	switch {
+	case t == timeType:
+		return "string"
+	case t == rawMessageType:
+		return "unknown"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+
+		return g.typeOf(t.Elem()) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s>", g.typeOf(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, true)
+		}
+
+		return g.named(t)
+	}
+
+	return "unknown"
+}
+
+func (g *typeScriptGenerator) named(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := typeName(t)
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", typeName(t), i)
+	}
+
+	g.names[t] = name
+	g.used[name] = true
+
+	body := g.object(t, false)
+	fmt.Fprintf(g.output, "export interface %s %s\n\n", name, body)
+
+	return name
+}
+
+func (g *typeScriptGenerator) object(t reflect.Type, inline bool) string {
+	fields := jsonFields(t)
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	properties := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		optional := ""
+		if field.optional {
+			optional = "?"
+		}
+
+		properties = append(properties, fmt.Sprintf("%s%s: %s;", propertyName(field.name), optional, g.typeOf(field.typ)))
+	}
+
+	if inline {
+		return "{ " + strings.Join(properties, " ") + " }"
+	}
+
+	return "{\n  " + strings.Join(properties, "\n  ") + "\n}"
+}
+
+func propertyName(name string) string {
+	for i, char := range name {
+		if !unicode.IsLetter(char) && char != '_' && char != '$' && (i == 0 || !unicode.IsNumber(char)) {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+
+	return name
+}
+
+func docComment(meta Meta) string {
+	comment := strings.Join(strings.Fields(meta.Description), " ")
+	comment = strings.ReplaceAll(comment, "*/", "*\\/")
+
+	if meta.Deprecated {
+		comment = strings.TrimSpace("@deprecated " + comment)
+	}
+
+	return comment
+}
+
+func functionName(command string) string {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	name := ""
+
+	for i, word := range words {
+		runes := []rune(word)
+
+		if i == 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		} else {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+
+		name += string(runes)
+	}
+
+	if name == "" || unicode.IsNumber([]rune(name)[0]) {
+		name = "_" + name
+	}
+
+	return name
+}
@@ -0,0 +1,198 @@
+package cadet
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Validator interface {
+	Validate(v any) error
+}
+
+type ValidationErrors map[string]string
+
+type tagValidator struct{}
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + " " + e[field]
+	}
+
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+func (tagValidator) Validate(obj any) error {
+	errs := ValidationErrors{}
+	validateValue(reflect.ValueOf(obj), "", errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateValue(v reflect.Value, path string, errs ValidationErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+
+		for _, field := range jsonFields(v.Type()) {
+			value, err := v.FieldByIndexErr(field.index)
+			if err != nil {
+				continue
+			}
+
+			name := field.name
+			if path != "" {
+				name = path + "." + name
+			}
+
+			if message := validateField(value, field.validate); message != "" {
+				errs[name] = message
+				continue
+			}
+
+			validateValue(value, name, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func validateField(v reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+
+	rules := strings.Split(tag, ",")
+	present := false
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if slices.Contains(rules, "required") {
+				return "is required"
+			}
+
+			return ""
+		}
+
+		v = v.Elem()
+		present = true
+	}
+
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "omitempty":
+			if !present && v.IsZero() {
+				return ""
+			}
+		case "required":
+			if !present && v.IsZero() {
+				return "is required"
+			}
+		case "min", "max", "len":
+			if message := validateSize(v, name, param); message != "" {
+				return message
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+				return "must be one of " + strings.Join(options, ", ")
+			}
+		case "email":
+			address, err := mail.ParseAddress(v.String())
+			if v.Kind() != reflect.String || err != nil || address.Address != v.String() {
+				return "must be a valid email address"
+			}
+		}
+	}
+
+	return ""
+}
+
+func validateSize(v reflect.Value, rule string, param string) string {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return ""
+	}
+
+	var size float64
+	var unit string
+
+	switch v.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		return ""
+	}
+
+	switch {
+	case rule == "min" && size < limit:
+		return "must be at least " + param + unit
+	case rule == "max" && size > limit:
+		return "must be at most " + param + unit
+	case rule == "len" && size != limit:
+		return "must be exactly " + param + unit
+	}
+
+	return ""
+}
+
+func ValidationError(fields map[string]string) Response {
+	return func(w http.ResponseWriter) {
+		type response struct {
+			Error  string            `json:"error"`
+			Fields map[string]string `json:"fields"`
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		JSON(&response{"validation failed", fields})(w)
+	}
+}
+
+func validationFailed(err error) Response {
+	if errs, ok := err.(ValidationErrors); ok {
+		return ValidationError(errs)
+	}
+
+	return Error(http.StatusBadRequest, err.Error())
+}
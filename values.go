@@ -0,0 +1,63 @@
+package cadet
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type valuesKey struct{}
+
+type values struct {
+	mu    sync.RWMutex
+	items map[string]any
+}
+
+func SetValue(r *http.Request, key string, value any) *http.Request {
+	if store := valuesFrom(r.Context()); store != nil {
+		store.set(key, value)
+		return r
+	}
+
+	store := &values{items: map[string]any{key: value}}
+	return r.WithContext(context.WithValue(r.Context(), valuesKey{}, store))
+}
+
+func Value[T any](r *Request, key string) (T, bool) {
+	var zero T
+
+	store := valuesFrom(r.Context())
+	if store == nil {
+		return zero, false
+	}
+
+	value, ok := store.get(key).(T)
+	if !ok {
+		return zero, false
+	}
+
+	return value, true
+}
+
+func (c *Request) Set(key string, value any) {
+	c.RawRequest = SetValue(c.RawRequest, key, value)
+}
+
+func (v *values) set(key string, value any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.items[key] = value
+}
+
+func (v *values) get(key string) any {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.items[key]
+}
+
+func valuesFrom(ctx context.Context) *values {
+	store, _ := ctx.Value(valuesKey{}).(*values)
+	return store
+}
@@ -0,0 +1,119 @@
+package cadet
+
+import "net/http"
+
+type responseWriter struct {
+	http.ResponseWriter
+	request *http.Request
+	fail    func(err error) Response
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type builderWriter struct {
+	http.ResponseWriter
+	status int
+	apply  func(http.Header)
+	wrote  bool
+}
+
+func (w *builderWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+
+	w.wrote = true
+
+	if w.apply != nil {
+		w.apply(w.Header())
+	}
+
+	if w.status != 0 {
+		status = w.status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *builderWriter) Write(data []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *builderWriter) finish() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (w *builderWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *builderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func requestFrom(w http.ResponseWriter) *http.Request {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw.request
+	}
+
+	return &http.Request{Method: http.MethodGet, Header: http.Header{}}
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *recordingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recordingWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}